@@ -1,14 +1,107 @@
 document.addEventListener('DOMContentLoaded', () => {
 
         fetchBackups();
+        fetchStatus();
+        subscribeToEvents();
 });
 
+// localStorage key of the API token, only needed once tokens are configured in the plugin settings
+const apiTokenKey = 'stationeersBackupManagerToken';
+
+// fetch with the stored API token. On 401 the user is asked for a token once and the request is retried.
+function apiFetch(url, options = {}, retried = false) {
+    const token = localStorage.getItem(apiTokenKey);
+    const headers = { ...(options.headers || {}) };
+    if (token) {
+        headers['Authorization'] = `Bearer ${token}`;
+    }
+    return fetch(url, { ...options, headers }).then(response => {
+        if (response.status !== 401 || retried) {
+            return response;
+        }
+        // Another request may have asked for a token in the meantime
+        if (localStorage.getItem(apiTokenKey) !== token) {
+            return apiFetch(url, options, true);
+        }
+        const entered = prompt('The backup manager API requires a token. Enter an API token:');
+        if (!entered) {
+            return response;
+        }
+        localStorage.setItem(apiTokenKey, entered.trim());
+        return apiFetch(url, options, true);
+    });
+}
+
+// Keep the backup list up to date using the plugin's Server-Sent Events stream
+function subscribeToEvents() {
+    const token = localStorage.getItem(apiTokenKey);
+    const query = token ? `?access_token=${encodeURIComponent(token)}` : '';
+    const source = new EventSource(`/plugins/StationeersBackupManager/api/v1/events${query}`);
+
+    source.addEventListener('backup.copied', () => { fetchBackups(); fetchStatus(); });
+    source.addEventListener('manager.reloaded', () => { fetchBackups(); fetchStatus(); });
+    source.addEventListener('restore.finished', () => fetchBackups());
+    source.addEventListener('backup.converted', () => fetchBackups());
+    source.addEventListener('backup.imported', () => fetchBackups());
+    source.addEventListener('backup.copy_failed', () => fetchStatus());
+    source.addEventListener('watcher.error', () => fetchStatus());
+
+    ['backup.copy_failed', 'restore.progress', 'restore.failed', 'watcher.error'].forEach(type => {
+        source.addEventListener(type, e => {
+            const event = JSON.parse(e.data);
+            showStatus(event.message);
+        });
+    });
+
+    source.onerror = () => console.warn('Backup event stream interrupted, the browser will reconnect automatically');
+}
+
+// Show a banner whenever the backup manager is not happily watching for autosaves
+function fetchStatus() {
+    return apiFetch('/plugins/StationeersBackupManager/api/v1/status')
+        .then(response => response.json())
+        .then(status => {
+            const banner = document.getElementById('statusBanner');
+            banner.className = `status-banner ${status.state}`;
+
+            const lastBackup = status.lastBackupAt ? ` Last backup: ${new Date(status.lastBackupAt).toLocaleString()}.` : '';
+            switch (status.state) {
+                case 'watching':
+                    banner.hidden = true;
+                    return;
+                case 'initializing':
+                    banner.textContent = 'Backup manager is starting up...';
+                    break;
+                case 'waiting-for-save-dir':
+                    banner.textContent = `Waiting for the save folder ${status.config.backupDir} to be created by Stationeers. Start the gameserver to begin backing up.`;
+                    break;
+                case 'degraded':
+                    banner.textContent = `Backup manager is degraded: ${status.lastError || 'unknown error'}.${lastBackup}`;
+                    break;
+                case 'stopped':
+                    banner.textContent = `Backup manager is stopped.${lastBackup}`;
+                    break;
+            }
+            banner.hidden = false;
+        })
+        .catch(err => console.error('Failed to fetch backup manager status:', err));
+}
+
+function showStatus(text) {
+    const status = document.getElementById('status');
+    status.hidden = false;
+    status.textContent = text;
+}
+
 
 function fetchBackups() {
+    // The history changes whenever the backup list does
+    fetchTree();
+
     const limit = document.getElementById('backupLimit').value;
     const url = limit ? `/plugins/StationeersBackupManager/api/v1/backups?limit=${limit}` : '/plugins/StationeersBackupManager/api/v1/backups';
     
-    return fetch(url)
+    return apiFetch(url)
         .then(response => {
             const contentType = response.headers.get('Content-Type');
             if (contentType && contentType.includes('application/json')) {
@@ -42,6 +135,7 @@ function fetchBackups() {
                 const formattedDate = "Created: " + new Date(backup.ModTime).toLocaleString();
                 
                 li.innerHTML = `
+                    ${thumbnailHtml(backup, backupType)}
                     <div class="backup-info">
                         <div class="backup-header">
                             <span class="backup-name">${fileName}</span>
@@ -68,6 +162,76 @@ function fetchBackups() {
         });
 }
 
+// Screenshot of a .save backup, or a placeholder for trios and saves without one.
+// img can't send headers, so the token goes in the query like for the event stream.
+function thumbnailHtml(backup, backupType) {
+    if (backupType !== 'Dotsave') {
+        return '<div class="backup-thumb placeholder">No preview</div>';
+    }
+    const token = localStorage.getItem(apiTokenKey);
+    const params = new URLSearchParams({ v: Date.parse(backup.ModTime) });
+    if (token) {
+        params.set('access_token', token);
+    }
+    const src = `/plugins/StationeersBackupManager/api/v1/backups/${encodeURIComponent(backup.ID)}/thumbnail?${params}`;
+    return `<img class="backup-thumb" src="${src}" alt="Screenshot of backup ${backup.Index}" loading="lazy" onerror="this.replaceWith(thumbnailPlaceholder())">`;
+}
+
+function thumbnailPlaceholder() {
+    const placeholder = document.createElement('div');
+    placeholder.className = 'backup-thumb placeholder';
+    placeholder.textContent = 'No preview';
+    return placeholder;
+}
+
+// Show the lineage of the backups. Each branch continues down its list, the live branch or the
+// newest child first, while other children start nested branches.
+function fetchTree() {
+    return apiFetch('/plugins/StationeersBackupManager/api/v1/backups/tree')
+        .then(response => response.ok ? response.json() : Promise.reject(new Error(response.statusText)))
+        .then(tree => {
+            const container = document.getElementById('backupTree');
+            container.innerHTML = '';
+
+            const byIndex = new Map(tree.nodes.map(node => [node.index, { ...node, children: [] }]));
+            const roots = [];
+            byIndex.forEach(node => {
+                const parent = node.parent !== null ? byIndex.get(node.parent) : undefined;
+                (parent ? parent.children : roots).push(node);
+            });
+            if (roots.length === 0) {
+                container.innerHTML = '<p class="no-backups">No backup history yet.</p>';
+                return;
+            }
+
+            const visited = new Set();
+            const renderBranch = start => {
+                const ul = document.createElement('ul');
+                for (let node = start; node && !visited.has(node.index);) {
+                    visited.add(node.index);
+                    const li = document.createElement('li');
+                    li.className = node.liveBranch ? 'live-branch' : '';
+                    li.textContent = `Backup ${node.index} (${node.type}) - ${new Date(node.createdAt).toLocaleString()}`;
+                    if (node.live) {
+                        const marker = document.createElement('span');
+                        marker.className = 'live-marker';
+                        marker.textContent = '● live';
+                        li.appendChild(marker);
+                    }
+                    ul.appendChild(li);
+
+                    const children = node.children.sort((a, b) => Date.parse(a.createdAt) - Date.parse(b.createdAt));
+                    const next = children.find(child => child.liveBranch) || children[children.length - 1];
+                    children.filter(child => child !== next).forEach(child => li.appendChild(renderBranch(child)));
+                    node = next;
+                }
+                return ul;
+            };
+            roots.forEach(root => container.appendChild(renderBranch(root)));
+        })
+        .catch(err => console.error('Failed to fetch backup history:', err));
+}
+
 function getBackupType(backup) {
     if (backup.BinFile && backup.XMLFile && backup.MetaFile) {
         return 'preterrain-trio';
@@ -82,38 +246,75 @@ function extractIndex(backupText) {
 }
 
 function restoreBackup(index) {
-    const status = document.getElementById('status');
-    fetch(`/plugins/StationeersBackupManager/api/v1/backups/restore?index=${index}`)
-        .then(response => response.text())
-        .then(data => {
-            status.hidden = false;
-            typeTextWithCallback(status, data, 20, () => {
-                setTimeout(() => status.hidden = true, 30000);
-            });
-        })
-        .catch(err => console.error(`Failed to restore backup ${index}:`, err));
+    apiFetch(`/plugins/StationeersBackupManager/api/v1/backups/restore?index=${index}&async=1`)
+        .then(response => response.ok ? response.headers.get('Location') : response.text().then(text => Promise.reject(new Error(text))))
+        .then(jobUrl => apiFetch(jobUrl))
+        .then(response => response.json())
+        .then(job => trackRestoreJob(job))
+        .catch(err => {
+            console.error(`Failed to restore backup ${index}:`, err);
+            showStatus(`Failed to restore backup ${index}: ${err.message}`);
+        });
 }
 
-// Utility function for typing text with a callback
-function typeTextWithCallback(element, text, speed, callback) {
-    if (element.dataset.isTyping === 'true') {
-        clearTimeout(element.dataset.timeoutId);
-    }
+// Poll a restore job and show its progress until it finishes
+function trackRestoreJob(job) {
+    const jobUrl = `/plugins/StationeersBackupManager/api/v2/jobs/${job.id}`;
+    const container = document.getElementById('restoreJob');
+    const progress = document.getElementById('restoreProgress');
+    const text = document.getElementById('restoreProgressText');
+    const cancel = document.getElementById('restoreCancel');
 
-    element.textContent = '';
-    element.dataset.isTyping = 'true';
-    let i = 0;
-    
-    const typeChar = () => {
-        if (i < text.length) {
-            element.textContent += text.charAt(i++);
-            const timeoutId = setTimeout(typeChar, speed);
-            element.dataset.timeoutId = timeoutId;
-        } else {
-            element.dataset.isTyping = 'false';
-            delete element.dataset.timeoutId;
-            if (callback) setTimeout(callback, 50);
+    container.hidden = false;
+    cancel.disabled = false;
+    cancel.onclick = () => {
+        cancel.disabled = true;
+        apiFetch(`${jobUrl}/cancel`, { method: 'POST' })
+            .catch(err => console.error(`Failed to cancel restore job ${job.id}:`, err));
+    };
+
+    const render = job => {
+        const percent = job.bytesTotal > 0 ? Math.floor(job.bytesDone * 100 / job.bytesTotal) : 0;
+        progress.value = percent;
+        switch (job.state) {
+            case 'queued':
+                text.textContent = `Backup ${job.backupIndex}: waiting for another restore to finish...`;
+                break;
+            case 'running':
+                text.textContent = `Restoring backup ${job.backupIndex}: ${percent}%`;
+                break;
         }
     };
-    typeChar();
-}
\ No newline at end of file
+
+    const finish = job => {
+        container.hidden = true;
+        switch (job.state) {
+            case 'succeeded':
+                showStatus(`Backup ${job.backupIndex} restored successfully, restart the server to load the restored backup`);
+                break;
+            case 'canceled':
+                showStatus(`Restore of backup ${job.backupIndex} was canceled, the live save was left unchanged`);
+                break;
+            default:
+                showStatus(`Restore of backup ${job.backupIndex} failed: ${job.error || 'unknown error'}`);
+        }
+    };
+
+    const poll = () => apiFetch(jobUrl)
+        .then(response => response.json())
+        .then(job => {
+            if (job.state === 'queued' || job.state === 'running') {
+                render(job);
+                setTimeout(poll, 500);
+            } else {
+                finish(job);
+            }
+        })
+        .catch(err => {
+            console.error(`Failed to fetch restore job ${job.id}:`, err);
+            setTimeout(poll, 2000);
+        });
+
+    render(job);
+    poll();
+}
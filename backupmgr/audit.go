@@ -0,0 +1,350 @@
+package backupmgr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SteamServerUI/PluginLib"
+	"github.com/SteamServerUI/StationeersBackupManager/global"
+)
+
+// Audit outcomes
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+	AuditDenied  = "denied"
+)
+
+// Audit actions that are not tied to an API route
+const (
+	AuditActionRestoreCompleted = "restore.completed"
+	AuditActionManagerReload    = "manager.reload"
+	AuditActionBackupPruned     = "backup.delete.pruned"
+)
+
+const (
+	// auditActorSystem is the actor of entries not caused by an API call
+	auditActorSystem = "system"
+	// auditActorAnonymous is the actor of API calls made while no tokens are configured
+	auditActorAnonymous = "anonymous"
+)
+
+const (
+	// auditMaxSize is the size at which the audit log is rotated
+	auditMaxSize = 5 << 20
+	// auditMaxFiles is the number of rotated audit logs kept next to the current one
+	auditMaxFiles = 3
+	// auditMaxBody is the largest request body recorded as audit parameters
+	auditMaxBody = 64 << 10
+)
+
+// AuditEntry is one line of the audit log
+type AuditEntry struct {
+	Time     time.Time      `json:"time"`
+	Action   string         `json:"action"`
+	Actor    string         `json:"actor"`
+	SourceIP string         `json:"sourceIp,omitempty"`
+	Method   string         `json:"method,omitempty"`
+	Path     string         `json:"path,omitempty"`
+	Params   map[string]any `json:"params,omitempty"`
+	Outcome  string         `json:"outcome"`
+	Status   int            `json:"status,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// auditLogger appends entries to a JSON-lines file and rotates it by size.
+// It is shared by all manager instances so the log spans reloads.
+type auditLogger struct {
+	mu   sync.Mutex
+	path string
+}
+
+var auditLog = &auditLogger{path: filepath.Join("./SSUI/plugins", global.PluginName, "audit.jsonl")}
+
+// record appends an entry to the audit log. Failures are logged but never block the audited operation.
+func (a *auditLogger) record(entry AuditEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		PluginLib.Log("Failed to encode audit entry: "+err.Error(), "Error")
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.rotateIfNeeded(int64(len(line)) + 1); err != nil {
+		PluginLib.Log("Failed to rotate audit log: "+err.Error(), "Error")
+	}
+	if err := os.MkdirAll(filepath.Dir(a.path), os.ModePerm); err != nil {
+		PluginLib.Log("Failed to create audit log directory: "+err.Error(), "Error")
+		return
+	}
+	f, err := os.OpenFile(a.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		PluginLib.Log("Failed to open audit log: "+err.Error(), "Error")
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		PluginLib.Log("Failed to write audit log: "+err.Error(), "Error")
+	}
+}
+
+// rotateIfNeeded shifts audit.jsonl to audit.jsonl.1 and so on once adding n bytes would exceed auditMaxSize.
+// The caller must hold a.mu.
+func (a *auditLogger) rotateIfNeeded(n int64) error {
+	info, err := os.Stat(a.path)
+	if err != nil || info.Size()+n <= auditMaxSize {
+		return nil
+	}
+
+	os.Remove(fmt.Sprintf("%s.%d", a.path, auditMaxFiles))
+	for i := auditMaxFiles - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", a.path, i)
+		if _, err := os.Stat(from); err == nil {
+			if err := os.Rename(from, fmt.Sprintf("%s.%d", a.path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Rename(a.path, a.path+".1")
+}
+
+// AuditFilter selects audit entries. Zero values match everything.
+type AuditFilter struct {
+	Action  string
+	Actor   string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+	Limit   int
+}
+
+func (f AuditFilter) matches(entry AuditEntry) bool {
+	switch {
+	case f.Action != "" && entry.Action != f.Action && !strings.HasPrefix(entry.Action, f.Action+"."):
+		return false
+	case f.Actor != "" && entry.Actor != f.Actor:
+		return false
+	case f.Outcome != "" && entry.Outcome != f.Outcome:
+		return false
+	case !f.Since.IsZero() && entry.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && entry.Time.After(f.Until):
+		return false
+	}
+	return true
+}
+
+// query returns the entries matching filter, newest first, across the current and rotated logs
+func (a *auditLogger) query(filter AuditFilter) ([]AuditEntry, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entries := []AuditEntry{}
+	paths := []string{a.path}
+	for i := 1; i <= auditMaxFiles; i++ {
+		paths = append(paths, fmt.Sprintf("%s.%d", a.path, i))
+	}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), auditMaxBody*2)
+		for scanner.Scan() {
+			var entry AuditEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue // skip lines torn by a crash
+			}
+			if filter.matches(entry) {
+				entries = append(entries, entry)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+// QueryAudit returns audit entries matching filter, newest first
+func QueryAudit(filter AuditFilter) ([]AuditEntry, error) {
+	return auditLog.query(filter)
+}
+
+// recordAudit appends an entry to the shared audit log
+func recordAudit(entry AuditEntry) {
+	auditLog.record(entry)
+}
+
+// auditPrune records a backup removed automatically by retention or the storage quota
+func auditPrune(group BackupGroup, reason string) {
+	recordAudit(AuditEntry{
+		Action:  AuditActionBackupPruned,
+		Actor:   auditActorSystem,
+		Params:  map[string]any{"backup": group.ID, "index": group.Index, "reason": reason},
+		Outcome: AuditSuccess,
+	})
+}
+
+// auditOutcome classifies a response status code
+func auditOutcome(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return AuditDenied
+	case status >= 400:
+		return AuditFailure
+	}
+	return AuditSuccess
+}
+
+// sourceIP returns the client address of a request. The X-Forwarded-For and X-Real-IP headers can be
+// set by any client, so they are only used when the request comes from a trusted proxy.
+func sourceIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	proxies := GetSettings().Auth.TrustedProxies
+	if !isTrustedProxy(remote, proxies) {
+		return remote
+	}
+
+	// Each proxy appends the address it received the request from, so walking the chain from the
+	// end the first address that isn't a trusted proxy is the client
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop != "" && (i == 0 || !isTrustedProxy(hop, proxies)) {
+				return hop
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
+	}
+	return remote
+}
+
+// parseTrustedProxy parses an entry of AuthSettings.TrustedProxies, an address or a CIDR range
+func parseTrustedProxy(entry string) (netip.Prefix, error) {
+	if strings.Contains(entry, "/") {
+		return netip.ParsePrefix(entry)
+	}
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// isTrustedProxy reports whether ip is one of the trusted proxies
+func isTrustedProxy(ip string, proxies []string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, entry := range proxies {
+		if prefix, err := parseTrustedProxy(entry); err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// auditParams collects the query, path and JSON body parameters of a request with secrets redacted.
+// The body is read and put back so the handler can still decode it.
+func auditParams(r *http.Request, route Route) map[string]any {
+	params := make(map[string]any)
+	for key, values := range r.URL.Query() {
+		if key == "access_token" {
+			continue
+		}
+		params[key] = strings.Join(values, ",")
+	}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+		params[match[1]] = r.PathValue(match[1])
+	}
+
+	if r.Body != nil {
+		body, err := io.ReadAll(io.LimitReader(r.Body, auditMaxBody))
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+		var decoded any
+		if err == nil && len(body) > 0 && json.Unmarshal(body, &decoded) == nil {
+			params["body"] = redactSecrets(decoded)
+		}
+	}
+
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+// redactSecrets replaces values of secret looking keys in a decoded JSON document
+func redactSecrets(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, inner := range v {
+			switch strings.ToLower(key) {
+			case "passphrase", "secret", "token":
+				v[key] = redactedSecret
+			default:
+				v[key] = redactSecrets(inner)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactSecrets(v[i])
+		}
+	}
+	return value
+}
+
+// actorKey is the request context key of the authenticated actor
+type actorKey struct{}
+
+// withActor stores the authenticated actor in the request context
+func withActor(r *http.Request, actor string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), actorKey{}, actor))
+}
+
+// requestActor returns the actor that authenticated a request
+func requestActor(r *http.Request) string {
+	if actor, ok := r.Context().Value(actorKey{}).(string); ok {
+		return actor
+	}
+	return auditActorAnonymous
+}
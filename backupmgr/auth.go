@@ -0,0 +1,197 @@
+package backupmgr
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/SteamServerUI/StationeersBackupManager/global"
+)
+
+// API token scopes. Each route operation requires one scope; admin grants all of them.
+const (
+	ScopeRead     = "read"
+	ScopeSnapshot = "snapshot"
+	ScopeRestore  = "restore"
+	ScopeDelete   = "delete"
+	ScopeAdmin    = "admin"
+)
+
+// minTokenLength is the minimum length of an API token
+const minTokenLength = 16
+
+// isKnownScope reports whether scope is one of the API token scopes
+func isKnownScope(scope string) bool {
+	switch scope {
+	case ScopeRead, ScopeSnapshot, ScopeRestore, ScopeDelete, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// APIToken grants access to the plugin API. Clients send it as "Authorization: Bearer <token>".
+type APIToken struct {
+	Name   string   `json:"name"`
+	Token  string   `json:"token"`
+	Scopes []string `json:"scopes"`
+}
+
+// allows reports whether the token grants the given scope
+func (t APIToken) allows(scope string) bool {
+	for _, granted := range t.Scopes {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// requestToken returns the API token sent with a request. Operations marked with QueryToken
+// also accept it as the access_token query parameter, see Operation.
+func requestToken(r *http.Request, op *Operation) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	if op != nil && op.QueryToken {
+		return r.URL.Query().Get("access_token")
+	}
+	return ""
+}
+
+// findToken returns the configured token matching value
+func findToken(tokens []APIToken, value string) (APIToken, bool) {
+	if value == "" {
+		return APIToken{}, false
+	}
+	for _, token := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token.Token), []byte(value)) == 1 {
+			return token, true
+		}
+	}
+	return APIToken{}, false
+}
+
+// writeAuthError answers an unauthorized request in the error format of the route's API version
+func writeAuthError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if strings.HasPrefix(r.URL.Path, "/api/v2/") {
+		code := "unauthorized"
+		switch status {
+		case http.StatusForbidden:
+			code = "forbidden"
+		case http.StatusServiceUnavailable:
+			code = errCodeUnavailable
+		}
+		writeAPIError(w, status, code, message)
+		return
+	}
+	http.Error(w, message, status)
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// rejectMethod answers a request with a method the route has no operation for, listing the allowed ones
+func rejectMethod(w http.ResponseWriter, r *http.Request, route Route) {
+	methods := make([]string, len(route.Operations))
+	for i, op := range route.Operations {
+		methods[i] = op.Method
+	}
+	allowed := strings.Join(methods, ", ")
+	if strings.HasPrefix(r.URL.Path, "/api/v2/") {
+		methodNotAllowed(w, allowed)
+		return
+	}
+	w.Header().Set("Allow", allowed)
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+}
+
+// authorize wraps a route handler so each operation requires a token with the operation's scope.
+// Routes without operations accept any valid token, methods a route doesn't list are refused with 405.
+// While no tokens are configured the API stays open. Operations with an audit action are recorded, see audit.go.
+func authorize(route Route) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var op *Operation
+		for i := range route.Operations {
+			if route.Operations[i].Method == r.Method {
+				op = &route.Operations[i]
+				break
+			}
+		}
+		if op == nil && len(route.Operations) > 0 {
+			rejectMethod(w, r, route)
+			return
+		}
+		// Locked settings may have lost the configured tokens, so nothing is served until they are fixed
+		if err := lockedSettingsError(); err != nil {
+			writeAuthError(w, r, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+
+		actor := auditActorAnonymous
+		if tokens := GetSettings().Auth.Tokens; len(tokens) > 0 {
+			token, ok := findToken(tokens, requestToken(r, op))
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+global.PluginName+`"`)
+				writeAuthError(w, r, http.StatusUnauthorized, "a valid API token is required")
+				if op != nil && op.Audit != "" {
+					auditRequest(r, route, *op, auditActorAnonymous, http.StatusUnauthorized)
+				}
+				return
+			}
+			if op != nil && !token.allows(op.Scope) {
+				writeAuthError(w, r, http.StatusForbidden, fmt.Sprintf("token %q lacks the %q scope", token.Name, op.Scope))
+				auditRequest(r, route, *op, token.Name, http.StatusForbidden)
+				return
+			}
+			actor = token.Name
+		}
+		r = withActor(r, actor)
+
+		if op == nil || op.Audit == "" {
+			route.Handler(w, r)
+			return
+		}
+		params := auditParams(r, route)
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		route.Handler(recorder, r)
+		recordAudit(AuditEntry{
+			Action:   op.Audit,
+			Actor:    actor,
+			SourceIP: sourceIP(r),
+			Method:   r.Method,
+			Path:     r.URL.Path,
+			Params:   params,
+			Outcome:  auditOutcome(recorder.status),
+			Status:   recorder.status,
+		})
+	}
+}
+
+// auditRequest records an API call that was rejected before reaching its handler
+func auditRequest(r *http.Request, route Route, op Operation, actor string, status int) {
+	action := op.Audit
+	if action == "" {
+		action = "api." + strings.ToLower(r.Method)
+	}
+	recordAudit(AuditEntry{
+		Action:   action,
+		Actor:    actor,
+		SourceIP: sourceIP(r),
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Params:   auditParams(r, route),
+		Outcome:  AuditDenied,
+		Status:   status,
+	})
+}
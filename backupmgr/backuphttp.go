@@ -1,11 +1,17 @@
 package backupmgr
 
 import (
+	"archive/zip"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/SteamServerUI/PluginLib"
 )
@@ -65,7 +71,8 @@ func (h *HTTPHandler) ListBackupsHandler(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(backups)
 }
 
-// RestoreBackupHandler handles requests to restore a backup
+// RestoreBackupHandler restores a backup and responds once the restore finished. With async=1 it
+// only queues the restore and points to the job, which can be followed through the v2 jobs API.
 func (h *HTTPHandler) RestoreBackupHandler(w http.ResponseWriter, r *http.Request) {
 	PluginLib.Log("Received restore request")
 	indexStr := r.URL.Query().Get("index")
@@ -82,10 +89,455 @@ func (h *HTTPHandler) RestoreBackupHandler(w http.ResponseWriter, r *http.Reques
 
 	//gamemgr.InternalStopServer() // TODO: CALL STOP VIA API INSTEAD
 
-	if err := h.manager.RestoreBackup(index); err != nil {
+	group, err := h.manager.GetBackupGroup(index)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	job, err := h.manager.EnqueueRestore(group, requestActor(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("async") == "1" {
+		w.Header().Set("Location", "/plugins/StationeersBackupManager/api/v2/jobs/"+job.ID)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("Restore queued as job " + job.ID))
+		return
+	}
+
+	// The restore keeps running if the client goes away, only the response is lost
+	job, err = jobs.wait(r.Context(), job.ID)
+	if err != nil {
+		return
+	}
+	switch job.State {
+	case JobSucceeded:
+		w.Write([]byte("Backup restored successfully, restart the server to load the restored backup"))
+	case JobCanceled:
+		http.Error(w, "restore was canceled, the live save was left unchanged", http.StatusInternalServerError)
+	default:
+		http.Error(w, job.Error, http.StatusInternalServerError)
+	}
+}
+
+// DownloadBackupHandler serves a backup for download, decrypting it if needed.
+// .save backups are served as-is, legacy trios are bundled into a zip archive.
+func (h *HTTPHandler) DownloadBackupHandler(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(r.URL.Query().Get("index"))
+	if err != nil {
+		http.Error(w, "invalid index parameter", http.StatusBadRequest)
+		return
+	}
+
+	group, err := h.manager.GetBackupGroup(index)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if isSaveFile(group.BinFile) {
+		f, err := h.manager.openBackupFile(group.BinFile)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", trimEncryptedSuffix(filepath.Base(group.BinFile))))
+		w.Header().Set("Content-Length", strconv.FormatInt(f.Size(), 10))
+		io.Copy(w, f)
+		return
+	}
+
+	// Open every part first, a missing or undecryptable file can still be reported as an error
+	paths := []string{group.XMLFile, group.MetaFile, group.BinFile}
+	sources := make([]io.ReadCloser, 0, len(paths))
+	defer func() {
+		for _, src := range sources {
+			src.Close()
+		}
+	}()
+	for _, path := range paths {
+		if path == "" {
+			http.Error(w, fmt.Sprintf("backup %d is incomplete", index), http.StatusNotFound)
+			return
+		}
+		src, err := h.manager.openBackupReader(path)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read %s: %s", filepath.Base(path), err.Error()), http.StatusInternalServerError)
+			return
+		}
+		sources = append(sources, src)
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"backup(%d).zip\"", index))
+	zw := zip.NewWriter(w)
+	for i, path := range paths {
+		fw, err := zw.Create(trimStorageSuffixes(filepath.Base(path)))
+		if err == nil {
+			_, err = io.Copy(fw, sources[i])
+		}
+		if err != nil {
+			PluginLib.Log(fmt.Sprintf("Failed to send %s for download: %s", path, err.Error()), "Error")
+			return
+		}
+	}
+	zw.Close()
+}
+
+// DiffBackupsHandler compares two backups given by the a and b query parameters
+func (h *HTTPHandler) DiffBackupsHandler(w http.ResponseWriter, r *http.Request) {
+	var indexes [2]int
+	for i, name := range []string{"a", "b"} {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			http.Error(w, name+" parameter is required", http.StatusBadRequest)
+			return
+		}
+		index, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "invalid "+name+" parameter", http.StatusBadRequest)
+			return
+		}
+		indexes[i] = index
+	}
+
+	diff, err := h.manager.DiffBackups(indexes[0], indexes[1])
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrBackupNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
+
+// InspectBackupHandler reports statistics about the world stored in a backup
+func (h *HTTPHandler) InspectBackupHandler(w http.ResponseWriter, r *http.Request) {
+	inspection, err := h.manager.InspectBackup(r.PathValue("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrBackupNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inspection)
+}
+
+// ThumbnailHandler serves the screenshot stored in a .save backup
+func (h *HTTPHandler) ThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := h.manager.Thumbnail(r.PathValue("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrBackupNotFound) || errors.Is(err, ErrNoThumbnail) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// Stored backups never change, so clients may cache the screenshot for a while
+	w.Header().Set("Content-Type", http.DetectContentType(data))
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Write(data)
+}
+
+// ConvertBackupHandler converts a backup to the other save format and responds with the conversion report
+func (h *HTTPHandler) ConvertBackupHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := h.manager.ConvertBackup(r.PathValue("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrBackupNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrConversionUnsupported):
+			status = http.StatusUnprocessableEntity
+		case errors.Is(err, errLockNotHeld):
+			status = http.StatusServiceUnavailable
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// ExportBundleHandler streams the backups given by the index parameter as a bundle
+func (h *HTTPHandler) ExportBundleHandler(w http.ResponseWriter, r *http.Request) {
+	indexParam := r.URL.Query().Get("index")
+	if indexParam == "" {
+		http.Error(w, "index parameter is required", http.StatusBadRequest)
+		return
+	}
+	var indexes []int
+	for _, value := range strings.Split(indexParam, ",") {
+		index, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			http.Error(w, "invalid index parameter", http.StatusBadRequest)
+			return
+		}
+		indexes = append(indexes, index)
+	}
+
+	groups, err := h.manager.backupGroupsByIndex(indexes)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrBackupNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="backups_%s.zip"`, time.Now().Format("2006-01-02_15-04-05")))
+	if err := h.manager.ExportBundle(w, groups); err != nil {
+		// Headers are gone already, the truncated archive won't pass the import checks
+		PluginLib.Log("Failed to export backup bundle: "+err.Error(), "Error")
+	}
+}
+
+// ImportBundleHandler stores the backups of a bundle sent as the request body
+func (h *HTTPHandler) ImportBundleHandler(w http.ResponseWriter, r *http.Request) {
+	// zip needs random access, so the upload is spooled to a temp file first
+	tmp, err := os.CreateTemp("", "backup-bundle-*.zip")
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	size, err := io.Copy(tmp, http.MaxBytesReader(w, r.Body, maxBundleSize))
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, "failed to read bundle: "+err.Error(), status)
+		return
+	}
 
-	w.Write([]byte("Backup restored successfully, restart the server to load the restored backup"))
+	report, err := h.manager.ImportBundle(tmp, size)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrInvalidBundle):
+			status = http.StatusUnprocessableEntity
+		case errors.Is(err, errLockNotHeld):
+			status = http.StatusServiceUnavailable
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// BackupTreeHandler returns the lineage of all backups
+func (h *HTTPHandler) BackupTreeHandler(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.manager.BackupTree()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tree)
+}
+
+// RotateKeyHandler re-encrypts all stored backups with a new passphrase or key file
+func (h *HTTPHandler) RotateKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Passphrase string `json:"passphrase"`
+		KeyFile    string `json:"keyFile"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	count, err := h.manager.RotateEncryptionKey(req.Passphrase, req.KeyFile)
+	if errors.Is(err, ErrInvalidKey) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte(fmt.Sprintf("Re-encrypted %d backup files with the new key", count)))
+}
+
+// sseKeepAliveInterval is how often a comment line is sent to keep idle event streams open
+const sseKeepAliveInterval = 25 * time.Second
+
+// EventsHandler streams backup manager activity as Server-Sent Events
+func (h *HTTPHandler) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	sub := events.subscribe()
+	defer events.unsubscribe(sub)
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case event, ok := <-sub:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// TestWebhooksHandler sends a test notification to all configured webhooks
+func (h *HTTPHandler) TestWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SendTestWebhook(r.Context()))
+}
+
+// MetricsHandler serves backup manager metrics in the Prometheus text format
+func (h *HTTPHandler) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	WriteMetrics(w, h.manager)
+}
+
+// StatusHandler reports the backup manager's lifecycle state
+func (h *HTTPHandler) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.manager.Status())
+}
+
+// ConfigHandler serves the plugin settings on GET and validates, persists and applies them on PUT
+func (h *HTTPHandler) ConfigHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GetSettings().Redacted())
+	case http.MethodPut:
+		// Decode on top of the current settings so omitted fields keep their values
+		settings := GetSettings().Redacted()
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&settings); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := UpdateSettings(settings); err != nil {
+			var validationErr *SettingsValidationError
+			if errors.As(err, &validationErr) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(validationErr)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GetSettings().Redacted())
+	}
+}
+
+// SnapshotHandler takes an immediate snapshot of the live save
+func (h *HTTPHandler) SnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	stored, err := h.manager.Snapshot()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stored)
+}
+
+// UsageHandler reports the disk usage of the backup set and the configured storage limits
+func (h *HTTPHandler) UsageHandler(w http.ResponseWriter, r *http.Request) {
+	usage, err := h.manager.Usage()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usage)
+}
+
+// AuditHandler returns audit log entries, newest first, filtered by action, actor, outcome and time range
+func (h *HTTPHandler) AuditHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := AuditFilter{
+		Action:  query.Get("action"),
+		Actor:   query.Get("actor"),
+		Outcome: query.Get("outcome"),
+		Limit:   100,
+	}
+	for name, target := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := query.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, name+" must be an RFC 3339 timestamp", http.StatusBadRequest)
+				return
+			}
+			*target = parsed
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			http.Error(w, "invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := QueryAudit(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
 }
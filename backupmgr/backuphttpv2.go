@@ -0,0 +1,416 @@
+package backupmgr
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+The v2 API exposes backups, restores and jobs as resources addressed by ID. Mutations use POST
+(PATCH for the notes and tags of a backup, DELETE for removing it) and every error is answered
+with the same JSON envelope:
+
+	{"error": {"code": "not_found", "message": "no backup found with id \"world(12).bin\""}}
+
+Backups are addressed by their ID, the name of their main file, which unlike the v1 index
+doesn't shift as older backups are pruned.
+
+The v1 handlers in backuphttp.go stay untouched so existing scripts keep working.
+*/
+
+// Error codes used in the v2 error envelope
+const (
+	errCodeInvalidParameter = "invalid_parameter"
+	errCodeNotFound         = "not_found"
+	errCodeMethodNotAllowed = "method_not_allowed"
+	errCodeConflict         = "conflict"
+	errCodeUnavailable      = "unavailable"
+	errCodeInternal         = "internal_error"
+)
+
+// Backup types as reported by the v2 API
+const (
+	backupTypeSave = "save"
+	backupTypeTrio = "trio"
+)
+
+const (
+	// defaultPageSize is the number of backups returned when no limit is given
+	defaultPageSize = 50
+	// maxPageSize caps the limit parameter
+	maxPageSize = 500
+)
+
+// APIError is the body of a v2 error response
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type errorEnvelope struct {
+	Error APIError `json:"error"`
+}
+
+// BackupResource is a backup as represented by the v2 API
+type BackupResource struct {
+	ID        string    `json:"id"`
+	Index     int       `json:"index"`
+	Type      string    `json:"type"`
+	Encrypted bool      `json:"encrypted"`
+	Files     []string  `json:"files"`
+	CreatedAt time.Time `json:"createdAt"`
+	Notes     string    `json:"notes,omitempty"`
+	Tags      []string  `json:"tags"`
+	Pinned    bool      `json:"pinned"`
+}
+
+// BackupPage is one page of a backup listing
+type BackupPage struct {
+	Items      []BackupResource `json:"items"`
+	NextCursor string           `json:"nextCursor,omitempty"`
+}
+
+// RestoreRequest is the body of POST /api/v2/restores
+type RestoreRequest struct {
+	BackupID string `json:"backupId"`
+}
+
+// newBackupResource converts a backup group and its catalog entry to its API representation
+func newBackupResource(group BackupGroup, entry CatalogEntry) BackupResource {
+	resource := BackupResource{ID: group.ID, Index: group.Index, Type: backupTypeTrio, CreatedAt: group.ModTime, Notes: entry.Notes, Tags: entry.Tags, Pinned: entry.Pinned}
+	if resource.Tags == nil {
+		resource.Tags = []string{}
+	}
+	if isSaveFile(group.BinFile) {
+		resource.Type = backupTypeSave
+	}
+	for _, path := range []string{group.BinFile, group.XMLFile, group.MetaFile} {
+		if path == "" {
+			continue
+		}
+		resource.Files = append(resource.Files, filepath.Base(path))
+		resource.Encrypted = resource.Encrypted || isEncryptedFile(path)
+	}
+	return resource
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError writes a v2 error envelope
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, errorEnvelope{Error: APIError{Code: code, Message: message}})
+}
+
+// writeAPIErrorFor maps an error returned by the backup manager to a v2 error response
+func writeAPIErrorFor(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidMetadata):
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidParameter, err.Error())
+	case errors.Is(err, ErrBackupNotFound), errors.Is(err, ErrJobNotFound):
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, err.Error())
+	case errors.Is(err, ErrJobFinished):
+		writeAPIError(w, http.StatusConflict, errCodeConflict, err.Error())
+	case errors.Is(err, errJobQueueFull), errors.Is(err, errLockNotHeld):
+		writeAPIError(w, http.StatusServiceUnavailable, errCodeUnavailable, err.Error())
+	default:
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, err.Error())
+	}
+}
+
+// methodNotAllowed answers a request whose method a v2 route does not support
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed, use "+allowed)
+}
+
+// backupCursor is the position of a backup in a listing, which is ordered by creation time and ID
+type backupCursor struct {
+	createdAt time.Time
+	id        string
+}
+
+// before reports whether a backup created at createdAt with the given ID is listed after the cursor
+func (c backupCursor) before(createdAt time.Time, id string) bool {
+	if !createdAt.Equal(c.createdAt) {
+		return createdAt.Before(c.createdAt)
+	}
+	return id < c.id
+}
+
+// encodeCursor turns the last returned backup into an opaque cursor
+func encodeCursor(resource BackupResource) string {
+	raw := strconv.FormatInt(resource.CreatedAt.UnixNano(), 10) + ":" + resource.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor reverses encodeCursor
+func decodeCursor(cursor string) (backupCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return backupCursor{}, err
+	}
+	nanos, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return backupCursor{}, errors.New("cursor has no backup id")
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return backupCursor{}, err
+	}
+	return backupCursor{createdAt: time.Unix(0, unixNano), id: id}, nil
+}
+
+// backupFilter holds the parsed filters of a backup listing
+type backupFilter struct {
+	since, until time.Time
+	backupType   string
+	tags         []string
+}
+
+func (f backupFilter) matches(resource BackupResource) bool {
+	if !f.since.IsZero() && resource.CreatedAt.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && resource.CreatedAt.After(f.until) {
+		return false
+	}
+	if f.backupType != "" && resource.Type != f.backupType {
+		return false
+	}
+	for _, tag := range f.tags {
+		if !slices.Contains(resource.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseBackupFilter reads the since, until, type and tag query parameters
+func parseBackupFilter(r *http.Request) (backupFilter, error) {
+	var filter backupFilter
+	query := r.URL.Query()
+	for name, target := range map[string]*time.Time{"since": &filter.since, "until": &filter.until} {
+		if value := query.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, errors.New(name + " must be an RFC 3339 timestamp")
+			}
+			*target = parsed
+		}
+	}
+	switch filter.backupType = query.Get("type"); filter.backupType {
+	case "", backupTypeSave, backupTypeTrio:
+	default:
+		return filter, errors.New("type must be " + backupTypeSave + " or " + backupTypeTrio)
+	}
+	for _, tag := range query["tag"] {
+		if strings.TrimSpace(tag) == "" {
+			return filter, errors.New("tag must not be empty")
+		}
+		filter.tags = append(filter.tags, strings.TrimSpace(tag))
+	}
+	return filter, nil
+}
+
+// BackupsV2Handler lists backups newest first, with cursor pagination and filters
+// GET /api/v2/backups?limit=&cursor=&since=&until=&type=&tag=
+func (h *HTTPHandler) BackupsV2Handler(w http.ResponseWriter, r *http.Request) {
+	limit := defaultPageSize
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxPageSize {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(maxPageSize))
+			return
+		}
+		limit = parsed
+	}
+
+	// Backups are ordered newest first, the cursor is the last backup of the previous page
+	var after *backupCursor
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		decoded, err := decodeCursor(cursor)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidParameter, "invalid cursor")
+			return
+		}
+		after = &decoded
+	}
+
+	filter, err := parseBackupFilter(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidParameter, err.Error())
+		return
+	}
+
+	groups, err := h.manager.ListBackups(0)
+	if err != nil {
+		writeAPIErrorFor(w, err)
+		return
+	}
+	entries, err := h.manager.CatalogEntries()
+	if err != nil {
+		writeAPIErrorFor(w, err)
+		return
+	}
+
+	// IDs break ties so backups taken within the same moment keep a stable order
+	sort.Slice(groups, func(i, j int) bool {
+		if !groups[i].ModTime.Equal(groups[j].ModTime) {
+			return groups[i].ModTime.After(groups[j].ModTime)
+		}
+		return groups[i].ID > groups[j].ID
+	})
+
+	page := BackupPage{Items: []BackupResource{}}
+	for _, group := range groups {
+		if after != nil && !after.before(group.ModTime, group.ID) {
+			continue
+		}
+		resource := newBackupResource(group, entries[group.ID])
+		if !filter.matches(resource) {
+			continue
+		}
+		if len(page.Items) == limit {
+			page.NextCursor = encodeCursor(page.Items[len(page.Items)-1])
+			break
+		}
+		page.Items = append(page.Items, resource)
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// BackupV2Handler returns a single backup on GET, changes its notes and tags on PATCH and deletes it on DELETE
+// GET|PATCH|DELETE /api/v2/backups/{id}
+func (h *HTTPHandler) BackupV2Handler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	switch r.Method {
+	case http.MethodGet:
+		h.writeBackupResource(w, id)
+	case http.MethodPatch:
+		var change BackupMetadata
+		if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidParameter, "invalid request body: "+err.Error())
+			return
+		}
+		if _, err := h.manager.UpdateBackupMetadata(id, change); err != nil {
+			writeAPIErrorFor(w, err)
+			return
+		}
+		h.writeBackupResource(w, id)
+	case http.MethodDelete:
+		if err := h.manager.DeleteBackup(id); err != nil {
+			writeAPIErrorFor(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// PinV2Handler pins a backup on PUT and unpins it on DELETE. Pinned backups are kept by retention and the quota.
+// PUT|DELETE /api/v2/backups/{id}/pin
+func (h *HTTPHandler) PinV2Handler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := h.manager.SetBackupPinned(id, r.Method == http.MethodPut); err != nil {
+		writeAPIErrorFor(w, err)
+		return
+	}
+	h.writeBackupResource(w, id)
+}
+
+// writeBackupResource responds with the backup with the given ID
+func (h *HTTPHandler) writeBackupResource(w http.ResponseWriter, id string) {
+	group, err := h.manager.GetBackup(id)
+	if err != nil {
+		writeAPIErrorFor(w, err)
+		return
+	}
+	entries, err := h.manager.CatalogEntries()
+	if err != nil {
+		writeAPIErrorFor(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newBackupResource(group, entries[id]))
+}
+
+// SnapshotsV2Handler takes an immediate snapshot of the live save
+// POST /api/v2/snapshots
+func (h *HTTPHandler) SnapshotsV2Handler(w http.ResponseWriter, r *http.Request) {
+	stored, err := h.manager.Snapshot()
+	if err != nil {
+		writeAPIErrorFor(w, err)
+		return
+	}
+	files := make([]string, 0, len(stored))
+	for _, path := range stored {
+		files = append(files, filepath.Base(path))
+	}
+	writeJSON(w, http.StatusCreated, map[string][]string{"files": files})
+}
+
+// RestoresV2Handler queues a restore and responds with the restore job
+// POST /api/v2/restores {"backupId": "world(12).bin"}
+func (h *HTTPHandler) RestoresV2Handler(w http.ResponseWriter, r *http.Request) {
+	var req RestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidParameter, "invalid request body: "+err.Error())
+		return
+	}
+	if req.BackupID == "" {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidParameter, "backupId is required")
+		return
+	}
+
+	group, err := h.manager.GetBackup(req.BackupID)
+	if err != nil {
+		writeAPIErrorFor(w, err)
+		return
+	}
+	job, err := h.manager.EnqueueRestore(group, requestActor(r))
+	if err != nil {
+		writeAPIErrorFor(w, err)
+		return
+	}
+	w.Header().Set("Location", "/plugins/StationeersBackupManager/api/v2/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// JobV2Handler returns the state of a background job
+// GET /api/v2/jobs/{id}
+func (h *HTTPHandler) JobV2Handler(w http.ResponseWriter, r *http.Request) {
+	job, err := GetJob(r.PathValue("id"))
+	if err != nil {
+		writeAPIErrorFor(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// CancelJobV2Handler requests cancellation of a queued or running job
+// POST /api/v2/jobs/{id}/cancel
+func (h *HTTPHandler) CancelJobV2Handler(w http.ResponseWriter, r *http.Request) {
+	job, err := CancelJob(r.PathValue("id"))
+	if err != nil {
+		writeAPIErrorFor(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// NotFoundV2Handler answers requests to unknown v2 routes with the JSON error envelope
+func (h *HTTPHandler) NotFoundV2Handler(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, http.StatusNotFound, errCodeNotFound, "no such route: "+r.URL.Path)
+}
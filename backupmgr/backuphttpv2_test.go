@@ -0,0 +1,140 @@
+package backupmgr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newV2TestHandler returns a handler for a backup set of .save archives and a trio. Two backups
+// share a modification time to exercise the ID tie break, newer backups have higher numbers.
+func newV2TestHandler(t *testing.T) (*HTTPHandler, time.Time) {
+	t.Helper()
+	cfg := testConfig(t.TempDir(), "Mars")
+	m := newTestManager(t, cfg)
+
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	files := []struct {
+		names []string
+		hours int
+		tags  []string
+	}{
+		{[]string{"save1.save"}, 0, []string{"daily"}},
+		{[]string{"world(100).xml", "world_meta(100).xml", "world(100).bin"}, 1, []string{"daily", "before-update"}},
+		{[]string{"save3.save"}, 2, nil},
+		{[]string{"save4.save"}, 2, []string{"daily"}},
+		{[]string{"save5.save"}, 3, []string{"before-update"}},
+	}
+	for _, file := range files {
+		modTime := base.Add(time.Duration(file.hours) * time.Hour)
+		for _, name := range file.names {
+			path := filepath.Join(cfg.SafeBackupDir, name)
+			if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(path, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if file.tags != nil {
+			id := file.names[len(file.names)-1]
+			if _, err := m.UpdateBackupMetadata(id, BackupMetadata{Tags: &file.tags}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return &HTTPHandler{manager: m}, base
+}
+
+// listBackupsV2 calls the v2 listing with the given query
+func listBackupsV2(t *testing.T, h *HTTPHandler, query url.Values) (int, BackupPage) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.BackupsV2Handler(rec, httptest.NewRequest(http.MethodGet, "/api/v2/backups?"+query.Encode(), nil))
+	var page BackupPage
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, page
+}
+
+// itemIDs returns the IDs of a page
+func itemIDs(page BackupPage) []string {
+	var ids []string
+	for _, item := range page.Items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestBackupsV2Pagination(t *testing.T) {
+	h, _ := newV2TestHandler(t)
+	want := []string{"save5.save", "save4.save", "save3.save", "world(100).bin", "save1.save"}
+
+	for _, limit := range []string{"1", "2", "3", "5", "50"} {
+		t.Run("limit "+limit, func(t *testing.T) {
+			var ids []string
+			query := url.Values{"limit": {limit}}
+			for pages := 0; ; pages++ {
+				if pages > len(want) {
+					t.Fatal("pagination did not end")
+				}
+				status, page := listBackupsV2(t, h, query)
+				if status != http.StatusOK {
+					t.Fatalf("status = %d", status)
+				}
+				ids = append(ids, itemIDs(page)...)
+				if page.NextCursor == "" {
+					break
+				}
+				query.Set("cursor", page.NextCursor)
+			}
+			if !slices.Equal(ids, want) {
+				t.Errorf("paged through %v, want %v", ids, want)
+			}
+		})
+	}
+}
+
+func TestBackupsV2Filters(t *testing.T) {
+	h, base := newV2TestHandler(t)
+	tests := []struct {
+		name   string
+		query  url.Values
+		status int
+		want   []string
+	}{
+		{"type save", url.Values{"type": {"save"}}, http.StatusOK, []string{"save5.save", "save4.save", "save3.save", "save1.save"}},
+		{"type trio", url.Values{"type": {"trio"}}, http.StatusOK, []string{"world(100).bin"}},
+		{"tag", url.Values{"tag": {"daily"}}, http.StatusOK, []string{"save4.save", "world(100).bin", "save1.save"}},
+		{"all tags", url.Values{"tag": {"daily", "before-update"}}, http.StatusOK, []string{"world(100).bin"}},
+		{"unknown tag", url.Values{"tag": {"weekly"}}, http.StatusOK, nil},
+		{"since", url.Values{"since": {base.Add(2 * time.Hour).Format(time.RFC3339)}}, http.StatusOK, []string{"save5.save", "save4.save", "save3.save"}},
+		{"until", url.Values{"until": {base.Add(time.Hour).Format(time.RFC3339)}}, http.StatusOK, []string{"world(100).bin", "save1.save"}},
+		{"tag and type", url.Values{"tag": {"before-update"}, "type": {"save"}}, http.StatusOK, []string{"save5.save"}},
+		{"empty tag", url.Values{"tag": {" "}}, http.StatusBadRequest, nil},
+		{"unknown type", url.Values{"type": {"zip"}}, http.StatusBadRequest, nil},
+		{"invalid since", url.Values{"since": {"yesterday"}}, http.StatusBadRequest, nil},
+		{"limit too large", url.Values{"limit": {"501"}}, http.StatusBadRequest, nil},
+		{"invalid cursor", url.Values{"cursor": {"not a cursor!"}}, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, page := listBackupsV2(t, h, tt.query)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+			if ids := itemIDs(page); !slices.Equal(ids, tt.want) {
+				t.Errorf("listed %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
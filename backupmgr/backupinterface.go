@@ -1,8 +1,8 @@
 package backupmgr
 
 import (
+	"encoding/json"
 	"fmt"
-	"os"
 	"sync"
 	"time"
 
@@ -36,6 +36,8 @@ func InitGlobalBackupManager(config BackupConfig) error {
 	manager := NewBackupManager(config)
 	GlobalBackupManager = manager
 
+	webhooks.setTargets(config.Webhooks)
+
 	// Update all active HTTP handlers with the new manager
 	for _, handler := range activeHTTPHandlers {
 		handler.manager = GlobalBackupManager
@@ -49,44 +51,80 @@ func InitGlobalBackupManager(config BackupConfig) error {
 	}(manager)
 
 	PluginLib.Log(fmt.Sprintf("%s Backup manager reloaded successfully", config.Identifier), "Debug")
+	recordAudit(AuditEntry{
+		Action:  AuditActionManagerReload,
+		Actor:   auditActorSystem,
+		Params:  map[string]any{"identifier": config.Identifier, "saveName": config.SaveName, "safeBackupDir": config.SafeBackupDir},
+		Outcome: AuditSuccess,
+	})
+	publishEvent(EventManagerReloaded, config.Identifier, "Backup manager reloaded", map[string]any{"backupDir": config.BackupDir, "safeBackupDir": config.SafeBackupDir})
 	return nil
 }
 
+// ShutdownGlobalBackupManager stops the global backup manager, waiting at most timeout for
+// pending copies, restores and webhook deliveries. It is meant to be called once when the plugin exits.
+func ShutdownGlobalBackupManager(timeout time.Duration) error {
+	initMutex.Lock()
+	defer initMutex.Unlock()
+
+	deadline := time.Now().Add(timeout)
+	var err error
+	if GlobalBackupManager != nil {
+		err = GlobalBackupManager.ShutdownWithin(timeout)
+		GlobalBackupManager = nil
+	}
+
+	// Deliveries of the final events get whatever is left of the deadline
+	if !webhooks.drain(time.Until(deadline)) && err == nil {
+		err = fmt.Errorf("%w: webhook deliveries were abandoned", ErrShutdownTimeout)
+	}
+	return err
+}
+
 // RegisterHTTPHandler registers an HTTP handler to be updated when the manager changes
 func RegisterHTTPHandler(handler *HTTPHandler) {
 	activeHTTPHandlers = append(activeHTTPHandlers, handler)
 }
 
-// GetBackupConfig returns a properly configured BackupConfig
-func GetBackupConfig() BackupConfig {
+// GetBackupConfig returns a properly configured BackupConfig built from the runfile and the plugin settings
+func GetBackupConfig() (BackupConfig, error) {
+	settings := GetSettings()
+	if err := lockedSettingsError(); err != nil {
+		return BackupConfig{}, err
+	}
 
 	saveName, err := getSaveNameFromSSUIRunfile()
 	if err != nil {
-		fmt.Println(err.Error())
-		os.Exit(2)
+		return BackupConfig{}, err
 	}
 
 	runfileIdentifier, err := getRfIdentifierFromSSUIRunfile()
 	if err != nil {
-		fmt.Println(err.Error())
-		os.Exit(3)
+		return BackupConfig{}, err
 	}
 
 	id := uuid.New()
 	bmIdentifier := "[BM" + id.String()[:6] + "]:"
-	return BackupConfig{
-		WorldName:     "SaveName",
-		BackupDir:     "./" + runfileIdentifier + "/saves/" + saveName + "/autosave",
-		SafeBackupDir: "./" + runfileIdentifier + "/saves/" + saveName + "/Safebackups",
-		WaitTime:      20 * time.Second,
-		Identifier:    bmIdentifier,
+	config := BackupConfig{
+		WorldName:         "SaveName",
+		SaveName:          saveName,
+		RunfileIdentifier: runfileIdentifier,
+		SaveDir:           "./" + runfileIdentifier + "/saves/" + saveName,
+		BackupDir:         "./" + runfileIdentifier + "/saves/" + saveName + "/autosave",
+		SafeBackupDir:     "./" + runfileIdentifier + "/saves/" + saveName + "/Safebackups",
+		Identifier:        bmIdentifier,
 	}
+	settings.applyTo(&config)
+	return config, nil
 }
 
 // ReloadBackupManagerFromConfig reloads the global backup manager with the current config. This should be called whenever the config is changed.
 func ReloadBackupManagerFromConfig() error {
 	// Create a new backupManager config from the global config
-	backupConfig := GetBackupConfig()
+	backupConfig, err := GetBackupConfig()
+	if err != nil {
+		return err
+	}
 
 	// Reinitialize the global backup manager with the new config
 	return InitGlobalBackupManager(backupConfig)
@@ -112,3 +150,39 @@ func getRfIdentifierFromSSUIRunfile() (string, error) {
 	}
 	return runfileIdentifierStr, nil
 }
+
+// getOptionalStringSetting returns a string setting from SSUI, or an empty string if it is not set
+func getOptionalStringSetting(name string) string {
+	value, err := PluginLib.GetSetting(name)
+	if err != nil {
+		return ""
+	}
+	str, _ := value.(string)
+	return str
+}
+
+// getWebhookTargetsSetting parses the BackupWebhooks setting, a JSON array of webhook targets
+func getWebhookTargetsSetting() []WebhookTarget {
+	raw := getOptionalStringSetting("BackupWebhooks")
+	if raw == "" {
+		return nil
+	}
+	var targets []WebhookTarget
+	if err := json.Unmarshal([]byte(raw), &targets); err != nil {
+		PluginLib.Log("Ignoring invalid BackupWebhooks setting: "+err.Error(), "Error")
+		return nil
+	}
+	return targets
+}
+
+// saveSettings persists the given settings through SSUI
+func saveSettings(settings map[string]string) error {
+	var response PluginLib.SettingsResponse
+	if _, err := PluginLib.Post("/api/v2/settings/save", &settings, &response); err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	if response.Status != "success" {
+		return fmt.Errorf("failed to save settings: %s", response.Message)
+	}
+	return nil
+}
@@ -0,0 +1,515 @@
+package backupmgr
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/SteamServerUI/PluginLib"
+	"github.com/SteamServerUI/StationeersBackupManager/global"
+)
+
+/*
+A bundle is a zip archive for moving backups between hosts:
+
+	backups/1/world(12).xml       plaintext files of the first backup
+	backups/1/world_meta(12).xml
+	backups/1/world(12).bin
+	backups/2/autosave.save       a .save backup
+	manifest.json                 written last, once all hashes are known
+
+Files are exported decrypted so the importing host can store them with its own key.
+*/
+
+const (
+	// bundleFormatVersion is the manifest format written by ExportBundle
+	bundleFormatVersion = 1
+	bundleManifestName  = "manifest.json"
+	// maxBundleSize caps an uploaded bundle, which is spooled to a temp file before it can be checked
+	maxBundleSize = 16 << 30
+)
+
+// ErrInvalidBundle is returned when an imported bundle is malformed or its hashes don't match
+var ErrInvalidBundle = errors.New("invalid backup bundle")
+
+// BundleManifest describes the contents of a bundle
+type BundleManifest struct {
+	FormatVersion     int            `json:"formatVersion"`
+	CreatedAt         time.Time      `json:"createdAt"`
+	Plugin            string         `json:"plugin"`
+	Host              string         `json:"host,omitempty"`
+	WorldName         string         `json:"worldName"`
+	RunfileIdentifier string         `json:"runfileIdentifier"`
+	Backups           []BundleBackup `json:"backups"`
+}
+
+// BundleBackup is a backup group inside a bundle
+type BundleBackup struct {
+	Type       string            `json:"type"`
+	Index      int               `json:"index"` // index on the exporting host
+	CapturedAt time.Time         `json:"capturedAt"`
+	Notes      string            `json:"notes,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	Meta       map[string]string `json:"meta,omitempty"`
+	Files      []BundleFile      `json:"files"`
+}
+
+// BundleFile is a single file of a backup inside a bundle
+type BundleFile struct {
+	Path       string `json:"path"`       // entry name in the bundle
+	StoredName string `json:"storedName"` // file name in SafeBackupDir, without the encryption suffix
+	Size       int64  `json:"size"`
+	SHA256     string `json:"sha256"`
+}
+
+// ImportReport describes the backups stored by ImportBundle
+type ImportReport struct {
+	WorldName         string           `json:"worldName"`
+	RunfileIdentifier string           `json:"runfileIdentifier"`
+	Backups           []ImportedBackup `json:"backups"`
+	Warnings          []string         `json:"warnings"`
+}
+
+// ImportedBackup is a backup stored by ImportBundle
+type ImportedBackup struct {
+	SourceIndex int       `json:"sourceIndex"`
+	ID          string    `json:"id"`
+	Index       int       `json:"index"`
+	CapturedAt  time.Time `json:"capturedAt"`
+	Files       []string  `json:"files"`
+}
+
+// backupFiles returns the stored files of a group, the .save archive or the trio parts
+func backupFiles(group BackupGroup) []string {
+	if isSaveFile(group.BinFile) {
+		return []string{group.BinFile}
+	}
+	return []string{group.XMLFile, group.MetaFile, group.BinFile}
+}
+
+// backupGroupsByIndex looks up the backup groups with the given indexes
+func (m *BackupManager) backupGroupsByIndex(indexes []int) ([]BackupGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	groups, err := m.getBackupGroups()
+	if err != nil {
+		return nil, err
+	}
+	byIndex := make(map[int]BackupGroup, len(groups))
+	for _, group := range groups {
+		byIndex[group.Index] = group
+	}
+
+	selected := make([]BackupGroup, 0, len(indexes))
+	for _, index := range indexes {
+		group, ok := byIndex[index]
+		if !ok {
+			return nil, fmt.Errorf("%w with index %d", ErrBackupNotFound, index)
+		}
+		selected = append(selected, group)
+	}
+	return selected, nil
+}
+
+// ExportBundle writes the given backups and their manifest as a bundle to w
+func (m *BackupManager) ExportBundle(w io.Writer, groups []BackupGroup) error {
+	m.mu.Lock()
+	catalog, err := m.loadCatalog()
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	host, _ := os.Hostname()
+	manifest := BundleManifest{
+		FormatVersion:     bundleFormatVersion,
+		CreatedAt:         time.Now().UTC(),
+		Plugin:            global.PluginName,
+		Host:              host,
+		WorldName:         m.config.SaveName,
+		RunfileIdentifier: m.config.RunfileIdentifier,
+	}
+
+	zw := zip.NewWriter(w)
+	for i, group := range groups {
+		entry := catalog.Backups[m.catalogKey(group)]
+		backup := BundleBackup{
+			Type:       backupTypeTrio,
+			Index:      group.Index,
+			CapturedAt: group.ModTime.UTC(),
+			Notes:      entry.Notes,
+			Tags:       entry.Tags,
+		}
+		if isSaveFile(group.BinFile) {
+			backup.Type = backupTypeSave
+		}
+		if !entry.CapturedAt.IsZero() {
+			backup.CapturedAt = entry.CapturedAt
+		}
+		if meta, err := m.readWorldMeta(group); err == nil {
+			backup.Meta = meta
+		} else {
+			PluginLib.Log(fmt.Sprintf("%s Exporting backup %d without parsed metadata: %s", m.config.Identifier, group.Index, err.Error()), "Debug")
+		}
+
+		for _, file := range backupFiles(group) {
+			storedName := trimStorageSuffixes(filepath.Base(file))
+			bundleFile, err := m.writeBundleFile(zw, file, fmt.Sprintf("backups/%d/%s", i+1, storedName), group.ModTime)
+			if err != nil {
+				return fmt.Errorf("failed to export %s: %w", storedName, err)
+			}
+			bundleFile.StoredName = storedName
+			backup.Files = append(backup.Files, bundleFile)
+		}
+		manifest.Backups = append(manifest.Backups, backup)
+	}
+
+	fw, err := zw.Create(bundleManifestName)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(fw)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// writeBundleFile copies a stored backup file, decrypted, into the bundle and hashes it
+func (m *BackupManager) writeBundleFile(zw *zip.Writer, src, name string, modified time.Time) (BundleFile, error) {
+	r, err := m.openBackupReader(src)
+	if err != nil {
+		return BundleFile{}, err
+	}
+	defer r.Close()
+
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return BundleFile{}, err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(fw, hash), r)
+	if err != nil {
+		return BundleFile{}, err
+	}
+	return BundleFile{Path: name, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// readBundleManifest reads and validates the manifest of a bundle
+func readBundleManifest(r *zip.Reader) (BundleManifest, map[string]*zip.File, error) {
+	entries := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		entries[f.Name] = f
+	}
+
+	var manifest BundleManifest
+	manifestFile, ok := entries[bundleManifestName]
+	if !ok {
+		return manifest, nil, fmt.Errorf("%w: %s is missing", ErrInvalidBundle, bundleManifestName)
+	}
+	rc, err := manifestFile.Open()
+	if err != nil {
+		return manifest, nil, err
+	}
+	defer rc.Close()
+	if err := json.NewDecoder(io.LimitReader(rc, 16<<20)).Decode(&manifest); err != nil {
+		return manifest, nil, fmt.Errorf("%w: failed to parse %s: %v", ErrInvalidBundle, bundleManifestName, err)
+	}
+
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > bundleFormatVersion {
+		return manifest, nil, fmt.Errorf("%w: unsupported format version %d", ErrInvalidBundle, manifest.FormatVersion)
+	}
+	if len(manifest.Backups) == 0 {
+		return manifest, nil, fmt.Errorf("%w: the bundle holds no backups", ErrInvalidBundle)
+	}
+	for i, backup := range manifest.Backups {
+		wantFiles := 3
+		if backup.Type == backupTypeSave {
+			wantFiles = 1
+		} else if backup.Type != backupTypeTrio {
+			return manifest, nil, fmt.Errorf("%w: backup %d has unknown type %q", ErrInvalidBundle, i+1, backup.Type)
+		}
+		if len(backup.Files) != wantFiles {
+			return manifest, nil, fmt.Errorf("%w: %s backup %d has %d files, expected %d", ErrInvalidBundle, backup.Type, i+1, len(backup.Files), wantFiles)
+		}
+		for _, file := range backup.Files {
+			// Stored names end up in SafeBackupDir, so only plain backup file names are accepted
+			name := file.StoredName
+			if name != filepath.Base(name) || strings.ContainsAny(name, `/\`) || !isValidBackupFile(name) || isEncryptedFile(name) {
+				return manifest, nil, fmt.Errorf("%w: invalid file name %q", ErrInvalidBundle, name)
+			}
+			if isSaveFile(name) != (backup.Type == backupTypeSave) {
+				return manifest, nil, fmt.Errorf("%w: %q does not belong in a %s backup", ErrInvalidBundle, name, backup.Type)
+			}
+			if entries[file.Path] == nil {
+				return manifest, nil, fmt.Errorf("%w: %s is missing", ErrInvalidBundle, file.Path)
+			}
+		}
+		if backup.CapturedAt.IsZero() {
+			manifest.Backups[i].CapturedAt = time.Now().UTC()
+		}
+		if backup.Type == backupTypeTrio && !isCompleteTrio(backup.Files) {
+			return manifest, nil, fmt.Errorf("%w: trio backup %d needs world.xml, world_meta.xml and world.bin of the same index", ErrInvalidBundle, i+1)
+		}
+	}
+	return manifest, entries, nil
+}
+
+// trioPartFormat returns the stored name format of a trio file, see trioParts
+func trioPartFormat(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".bin"):
+		return "world(%d).bin"
+	case strings.HasPrefix(name, "world_meta("):
+		return "world_meta(%d).xml"
+	}
+	return "world(%d).xml"
+}
+
+// isCompleteTrio reports whether files are the three parts of one trio
+func isCompleteTrio(files []BundleFile) bool {
+	parts := make(map[string]bool)
+	index := -1
+	for _, file := range files {
+		parts[trioPartFormat(file.StoredName)] = true
+		fileIndex := parseBackupIndex(file.StoredName, time.Time{}, nil)
+		if fileIndex < 0 || (index >= 0 && fileIndex != index) {
+			return false
+		}
+		index = fileIndex
+	}
+	return len(parts) == len(trioParts)
+}
+
+// ImportBundle stores the backups of a bundle in SafeBackupDir. Every file is extracted and checked
+// against the manifest hashes before anything is stored, and a failure while storing removes the
+// files stored so far, so a bundle is imported completely or not at all.
+func (m *BackupManager) ImportBundle(r io.ReaderAt, size int64) (ImportReport, error) {
+	if !m.holdsLock() {
+		return ImportReport{}, errLockNotHeld
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return ImportReport{}, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	manifest, entries, err := readBundleManifest(zr)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "backup-import-")
+	if err != nil {
+		return ImportReport{}, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// extracted[i][j] is the temp path of file j of backup i
+	extracted := make([][]string, len(manifest.Backups))
+	for i, backup := range manifest.Backups {
+		for j, file := range backup.Files {
+			dst := filepath.Join(tmpDir, fmt.Sprintf("%d-%d", i, j))
+			if err := extractVerified(entries[file.Path], dst, file); err != nil {
+				return ImportReport{}, err
+			}
+			extracted[i] = append(extracted[i], dst)
+		}
+	}
+
+	report := ImportReport{
+		WorldName:         manifest.WorldName,
+		RunfileIdentifier: manifest.RunfileIdentifier,
+		Backups:           []ImportedBackup{},
+		Warnings:          []string{},
+	}
+	if manifest.RunfileIdentifier != "" && manifest.RunfileIdentifier != m.config.RunfileIdentifier {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("the bundle was exported from %s, this server runs %s", manifest.RunfileIdentifier, m.config.RunfileIdentifier))
+	}
+
+	if err := m.storeImported(manifest, extracted, &report); err != nil {
+		return ImportReport{}, err
+	}
+
+	PluginLib.Log(fmt.Sprintf("%s Imported %d backups of %s from %s", m.config.Identifier, len(report.Backups), manifest.WorldName, manifest.Host), "Info")
+	publishEvent(EventBackupImported, m.config.Identifier, "Backups imported from a bundle", map[string]any{
+		"backups": len(report.Backups), "worldName": manifest.WorldName, "host": manifest.Host,
+	})
+	return report, nil
+}
+
+// extractVerified extracts a bundle entry to dst and checks its size and hash against the manifest
+func extractVerified(f *zip.File, dst string, file BundleFile) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: failed to open %s: %v", ErrInvalidBundle, file.Path, err)
+	}
+	defer rc.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	// Reading one byte past the declared size is enough to reject the entry, so a bundle can't
+	// write more to the temp dir than its manifest announces
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(out, hash), io.LimitReader(rc, file.Size+1))
+	if err != nil {
+		return fmt.Errorf("%w: failed to read %s: %v", ErrInvalidBundle, file.Path, err)
+	}
+	if written != file.Size || !strings.EqualFold(hex.EncodeToString(hash.Sum(nil)), file.SHA256) {
+		return fmt.Errorf("%w: %s does not match the hash in the manifest", ErrInvalidBundle, file.Path)
+	}
+	return out.Close()
+}
+
+// storeImported stores verified bundle files and records their metadata in the catalog.
+// Names already taken in SafeBackupDir are replaced by fresh ones, see importDestinations.
+func (m *BackupManager) storeImported(manifest BundleManifest, extracted [][]string, report *ImportReport) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.config.SafeBackupDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create safe backup dir: %w", err)
+	}
+
+	// Make room for the whole bundle up front, so the storage limits can't stop it halfway
+	var total int64
+	for _, files := range extracted {
+		for _, src := range files {
+			info, err := os.Stat(src)
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+	}
+	if err := m.ensureSpace(total); err != nil {
+		return err
+	}
+
+	var stored []string
+	rollback := func() {
+		for _, path := range stored {
+			os.Remove(path)
+		}
+	}
+
+	storedBackups := make([][]string, len(manifest.Backups))
+	for i, backup := range manifest.Backups {
+		destinations, err := m.importDestinations(backup)
+		if err != nil {
+			rollback()
+			return err
+		}
+		for j, src := range extracted[i] {
+			storedPath, err := m.storeBackupFile(src, destinations[j])
+			if err != nil {
+				rollback()
+				return fmt.Errorf("failed to store %s: %w", backup.Files[j].StoredName, err)
+			}
+			stored = append(stored, storedPath)
+			storedBackups[i] = append(storedBackups[i], storedPath)
+		}
+	}
+
+	groups, err := m.getBackupGroups()
+	if err != nil {
+		rollback()
+		return err
+	}
+	byFile := make(map[string]BackupGroup, len(groups))
+	for _, group := range groups {
+		byFile[group.BinFile] = group
+	}
+
+	now := time.Now().UTC()
+	err = m.updateCatalog(func(catalog *backupCatalog) bool {
+		for i, backup := range manifest.Backups {
+			files := storedBackups[i]
+			var group BackupGroup
+			for _, file := range files {
+				if found, ok := byFile[file]; ok {
+					group = found // the .save or world.bin
+				}
+			}
+			catalog.Backups[group.ID] = CatalogEntry{
+				Notes:        backup.Notes,
+				Tags:         backup.Tags,
+				CapturedAt:   backup.CapturedAt,
+				ImportedFrom: strings.TrimSpace(manifest.Host + " " + manifest.WorldName),
+				ImportedAt:   now,
+			}
+			report.Backups = append(report.Backups, ImportedBackup{
+				SourceIndex: backup.Index,
+				ID:          group.ID,
+				Index:       group.Index,
+				CapturedAt:  backup.CapturedAt,
+				Files:       files,
+			})
+		}
+		return true
+	})
+	if err != nil {
+		rollback()
+		return fmt.Errorf("failed to record imported backups in the catalog: %w", err)
+	}
+
+	m.refreshBackupSetStats()
+	for _, path := range stored {
+		m.replicate(path)
+	}
+	return nil
+}
+
+// importDestinations picks the paths for an imported backup. The original names are kept when they
+// are free; otherwise trios get a fresh snapshot index and .save archives an "imported_" prefix.
+// The caller must hold m.mu.
+func (m *BackupManager) importDestinations(backup BundleBackup) ([]string, error) {
+	taken := func(name string) bool {
+		for _, candidate := range storedVariants(name) {
+			if _, err := os.Stat(filepath.Join(m.config.SafeBackupDir, candidate)); err == nil {
+				return true
+			}
+		}
+		return false
+	}
+
+	var destinations []string
+	free := true
+	for _, file := range backup.Files {
+		free = free && !taken(file.StoredName)
+		destinations = append(destinations, filepath.Join(m.config.SafeBackupDir, file.StoredName))
+	}
+	if free {
+		return destinations, nil
+	}
+
+	if backup.Type == backupTypeSave {
+		name := fmt.Sprintf("imported_%s_%s", backup.CapturedAt.Format("2006-01-02_15-04-05"), backup.Files[0].StoredName)
+		if taken(name) {
+			return nil, fmt.Errorf("%s already exists", name)
+		}
+		return []string{filepath.Join(m.config.SafeBackupDir, name)}, nil
+	}
+
+	index, err := m.nextSnapshotIndex()
+	if err != nil {
+		return nil, err
+	}
+	destinations = destinations[:0]
+	for _, file := range backup.Files {
+		destinations = append(destinations, filepath.Join(m.config.SafeBackupDir, fmt.Sprintf(trioPartFormat(file.StoredName), index)))
+	}
+	return destinations, nil
+}
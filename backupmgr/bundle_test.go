@@ -0,0 +1,155 @@
+package backupmgr
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// buildBundle returns a bundle holding a single .save backup, edit can break its manifest entry
+func buildBundle(t *testing.T, content []byte, edit func(*BundleFile)) []byte {
+	t.Helper()
+	hash := sha256.Sum256(content)
+	file := BundleFile{
+		Path:       "backups/1/Mars.save",
+		StoredName: "Mars.save",
+		Size:       int64(len(content)),
+		SHA256:     hex.EncodeToString(hash[:]),
+	}
+	if edit != nil {
+		edit(&file)
+	}
+	manifest := BundleManifest{
+		FormatVersion: bundleFormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		WorldName:     "Mars",
+		Backups:       []BundleBackup{{Type: backupTypeSave, Index: 1, Files: []BundleFile{file}}},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	fw, err := zw.Create("backups/1/Mars.save")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(content)
+	fw, err = zw.Create(bundleManifestName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewEncoder(fw).Encode(manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImportBundle(t *testing.T) {
+	content := []byte("zipped world")
+	tests := []struct {
+		name  string
+		edit  func(*BundleFile)
+		valid bool
+	}{
+		{"matching manifest", nil, true},
+		{"uppercase hash", func(f *BundleFile) { f.SHA256 = string(bytes.ToUpper([]byte(f.SHA256))) }, true},
+		{"bad hash", func(f *BundleFile) { f.SHA256 = hex.EncodeToString(make([]byte, sha256.Size)) }, false},
+		{"entry larger than declared", func(f *BundleFile) { f.Size-- }, false},
+		{"entry smaller than declared", func(f *BundleFile) { f.Size++ }, false},
+		{"missing entry", func(f *BundleFile) { f.Path = "backups/1/Other.save" }, false},
+		{"path in stored name", func(f *BundleFile) { f.StoredName = "../Mars.save" }, false},
+		{"encrypted stored name", func(f *BundleFile) { f.StoredName = "Mars.save.enc" }, false},
+		{"trio part in a save backup", func(f *BundleFile) { f.StoredName = "world(1).bin" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t.TempDir(), "Mars")
+			m := newTestManager(t, cfg)
+
+			bundle := buildBundle(t, content, tt.edit)
+			report, err := m.ImportBundle(bytes.NewReader(bundle), int64(len(bundle)))
+			entries, readErr := os.ReadDir(cfg.SafeBackupDir)
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+			var stored []string
+			for _, entry := range entries {
+				if isValidBackupFile(entry.Name()) {
+					stored = append(stored, entry.Name())
+				}
+			}
+
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidBundle) {
+					t.Fatalf("ImportBundle() error = %v, want ErrInvalidBundle", err)
+				}
+				if len(stored) > 0 {
+					t.Errorf("invalid bundle stored %v", stored)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Backups) != 1 || len(stored) != 1 {
+				t.Fatalf("imported %d backups and stored %v, want 1", len(report.Backups), stored)
+			}
+			r, err := m.openBackupReader(filepath.Join(cfg.SafeBackupDir, stored[0]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("stored %q, want %q", got, content)
+			}
+		})
+	}
+}
+
+func TestReadBundleManifestRejectsBadBackups(t *testing.T) {
+	tests := []struct {
+		name   string
+		backup BundleBackup
+	}{
+		{"unknown type", BundleBackup{Type: "folder", Files: []BundleFile{{Path: "a", StoredName: "Mars.save"}}}},
+		{"save with two files", BundleBackup{Type: backupTypeSave, Files: []BundleFile{{Path: "a", StoredName: "a.save"}, {Path: "a", StoredName: "b.save"}}}},
+		{"trio of mixed indexes", BundleBackup{Type: backupTypeTrio, Files: []BundleFile{
+			{Path: "a", StoredName: "world(1).xml"}, {Path: "a", StoredName: "world_meta(1).xml"}, {Path: "a", StoredName: "world(2).bin"},
+		}}},
+		{"trio with a part twice", BundleBackup{Type: backupTypeTrio, Files: []BundleFile{
+			{Path: "a", StoredName: "world(1).xml"}, {Path: "a", StoredName: "world(1).xml"}, {Path: "a", StoredName: "world(1).bin"},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			zw := zip.NewWriter(&buf)
+			zw.Create("a")
+			fw, _ := zw.Create(bundleManifestName)
+			json.NewEncoder(fw).Encode(BundleManifest{FormatVersion: bundleFormatVersion, Backups: []BundleBackup{tt.backup}})
+			zw.Close()
+
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := readBundleManifest(zr); !errors.Is(err, ErrInvalidBundle) {
+				t.Errorf("readBundleManifest() error = %v, want ErrInvalidBundle", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package backupmgr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// catalogFile holds metadata about stored backups that their files cannot carry, like notes,
+// tags and where an imported backup came from. It lives in SafeBackupDir next to the backups.
+const catalogFile = "catalog.json"
+
+// backupCatalog is the content of the catalog file
+type backupCatalog struct {
+	Backups map[string]CatalogEntry `json:"backups"`
+	// Head is the backup the live save descends from, see lineage.go
+	Head string `json:"head,omitempty"`
+}
+
+// CatalogEntry is the metadata kept about a single stored backup
+type CatalogEntry struct {
+	Notes string   `json:"notes,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	// Pinned backups are never removed by retention or the storage quota
+	Pinned     bool      `json:"pinned,omitempty"`
+	CapturedAt time.Time `json:"capturedAt,omitzero"`
+	// ImportedFrom names the host and bundle an imported backup came from
+	ImportedFrom string    `json:"importedFrom,omitempty"`
+	ImportedAt   time.Time `json:"importedAt,omitzero"`
+	// Parent is the catalog key of the backup this one descends from, see lineage.go
+	Parent string `json:"parent,omitempty"`
+}
+
+// catalogKey identifies a backup in the catalog by the name of its main file relative to
+// SafeBackupDir. Indexes of .save backups shift as backups are pruned, file names don't,
+// and the compression and encryption suffixes are dropped so changing either keeps the entries.
+func (m *BackupManager) catalogKey(group BackupGroup) string {
+	rel, err := filepath.Rel(m.config.SafeBackupDir, group.BinFile)
+	if err != nil {
+		rel = filepath.Base(group.BinFile)
+	}
+	return filepath.ToSlash(trimStorageSuffixes(rel))
+}
+
+// loadCatalog reads the catalog, a missing catalog is empty. The caller must hold m.mu.
+func (m *BackupManager) loadCatalog() (*backupCatalog, error) {
+	catalog := &backupCatalog{}
+	data, err := os.ReadFile(filepath.Join(m.config.SafeBackupDir, catalogFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, catalog); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", catalogFile, err)
+		}
+	}
+	if catalog.Backups == nil {
+		catalog.Backups = make(map[string]CatalogEntry)
+	}
+	return catalog, nil
+}
+
+// saveCatalog replaces the catalog atomically. The caller must hold m.mu.
+func (m *BackupManager) saveCatalog(catalog *backupCatalog) error {
+	data, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(m.config.SafeBackupDir, catalogFile)
+	if err := writeFileSync(path+".tmp", data); err != nil {
+		os.Remove(path + ".tmp")
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// updateCatalog applies fn to the catalog and saves it if fn reports a change. The caller must hold m.mu.
+func (m *BackupManager) updateCatalog(fn func(catalog *backupCatalog) bool) error {
+	catalog, err := m.loadCatalog()
+	if err != nil {
+		return err
+	}
+	if !fn(catalog) {
+		return nil
+	}
+	return m.saveCatalog(catalog)
+}
+
+// remove drops a backup from the catalog and reports whether it was known. Its children are
+// attached to its parent so the lineage stays connected when backups are pruned.
+func (c *backupCatalog) remove(key string) bool {
+	removed, found := c.Backups[key]
+	if !found && c.Head != key {
+		return false
+	}
+	delete(c.Backups, key)
+	for childKey, child := range c.Backups {
+		if child.Parent == key {
+			child.Parent = removed.Parent
+			c.Backups[childKey] = child
+		}
+	}
+	if c.Head == key {
+		c.Head = removed.Parent
+	}
+	return true
+}
+
+// maxTagLength caps the length of a single backup tag
+const maxTagLength = 64
+
+// ErrInvalidMetadata is returned when notes or tags of a backup are rejected
+var ErrInvalidMetadata = errors.New("invalid backup metadata")
+
+// BackupMetadata is a change to the notes and tags of a backup. Nil fields are left unchanged.
+type BackupMetadata struct {
+	Notes *string   `json:"notes"`
+	Tags  *[]string `json:"tags"`
+}
+
+// CatalogEntries returns the catalog entries of the stored backups by backup ID
+func (m *BackupManager) CatalogEntries() (map[string]CatalogEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	catalog, err := m.loadCatalog()
+	if err != nil {
+		return nil, err
+	}
+	return catalog.Backups, nil
+}
+
+// UpdateBackupMetadata changes the notes and tags of the backup with the given ID and returns its entry.
+// Tags are trimmed and de-duplicated, empty ones are rejected.
+func (m *BackupManager) UpdateBackupMetadata(id string, change BackupMetadata) (CatalogEntry, error) {
+	var tags []string
+	if change.Tags != nil {
+		seen := make(map[string]bool)
+		for _, tag := range *change.Tags {
+			tag = strings.TrimSpace(tag)
+			if tag == "" || len(tag) > maxTagLength {
+				return CatalogEntry{}, fmt.Errorf("%w: tags must be 1 to %d characters", ErrInvalidMetadata, maxTagLength)
+			}
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.findBackup(id); err != nil {
+		return CatalogEntry{}, err
+	}
+	var entry CatalogEntry
+	err := m.updateCatalog(func(catalog *backupCatalog) bool {
+		entry = catalog.Backups[id]
+		if change.Notes != nil {
+			entry.Notes = *change.Notes
+		}
+		if change.Tags != nil {
+			entry.Tags = tags
+		}
+		catalog.Backups[id] = entry
+		return true
+	})
+	return entry, err
+}
+
+// SetBackupPinned pins or unpins the backup with the given ID and returns its entry
+func (m *BackupManager) SetBackupPinned(id string, pinned bool) (CatalogEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.findBackup(id); err != nil {
+		return CatalogEntry{}, err
+	}
+	var entry CatalogEntry
+	err := m.updateCatalog(func(catalog *backupCatalog) bool {
+		entry = catalog.Backups[id]
+		entry.Pinned = pinned
+		catalog.Backups[id] = entry
+		return true
+	})
+	return entry, err
+}
+
+// pinnedBackups returns the IDs of the pinned backups. The caller must hold m.mu.
+func (m *BackupManager) pinnedBackups() (map[string]bool, error) {
+	catalog, err := m.loadCatalog()
+	if err != nil {
+		return nil, err
+	}
+	pinned := make(map[string]bool)
+	for id, entry := range catalog.Backups {
+		if entry.Pinned {
+			pinned[id] = true
+		}
+	}
+	return pinned, nil
+}
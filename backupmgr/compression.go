@@ -0,0 +1,79 @@
+package backupmgr
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+)
+
+/*
+Legacy trio files (world.xml, world_meta.xml, world.bin) are stored uncompressed by the game and compress well.
+With compression enabled they are stored gzipped with an additional .gz suffix, in front of the encryption
+suffix if the backup is encrypted as well (e.g. world(3).bin.gz.enc). .save archives are zips already and
+are always stored as-is. Readers decompress transparently, see openBackupReader.
+*/
+
+const (
+	// CompressionNone stores backup files as they are
+	CompressionNone = "none"
+	// CompressionGzip stores legacy trio files gzipped
+	CompressionGzip = "gzip"
+
+	compressedSuffix = ".gz"
+)
+
+// isCompressedFile reports whether a (possibly encrypted) backup file is stored gzipped
+func isCompressedFile(path string) bool {
+	return strings.HasSuffix(trimEncryptedSuffix(path), compressedSuffix)
+}
+
+// trimStorageSuffixes returns the file name as it was before compression and encryption
+func trimStorageSuffixes(path string) string {
+	return strings.TrimSuffix(trimEncryptedSuffix(path), compressedSuffix)
+}
+
+// storedVariants returns every name a backup file stored as path may have on disk
+func storedVariants(path string) []string {
+	return []string{path, path + encryptedSuffix, path + compressedSuffix, path + compressedSuffix + encryptedSuffix}
+}
+
+// shouldCompress reports whether a file stored as dst is compressed with the current settings
+func (c BackupConfig) shouldCompress(dst string) bool {
+	return c.Compression == CompressionGzip && !isSaveFile(dst)
+}
+
+// compressStream returns a reader yielding the gzipped contents of src
+func compressStream(src io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		zw := gzip.NewWriter(pw)
+		_, err := io.Copy(zw, src)
+		if closeErr := zw.Close(); err == nil {
+			err = closeErr
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// gzipReadCloser decompresses a stored file and closes it along with the decompressor
+type gzipReadCloser struct {
+	*gzip.Reader
+	file io.Closer
+}
+
+// Close closes the decompressor and the underlying file
+func (r *gzipReadCloser) Close() error {
+	r.Reader.Close()
+	return r.file.Close()
+}
+
+// newDecompressingReader wraps a stored file in a gzip reader, closing the file if that fails
+func newDecompressingReader(f io.ReadCloser) (io.ReadCloser, error) {
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &gzipReadCloser{Reader: zr, file: f}, nil
+}
@@ -0,0 +1,63 @@
+package backupmgr
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreBackupFileCompression(t *testing.T) {
+	content := bytes.Repeat([]byte("<thing>atmosphere</thing>\n"), 10000)
+	tests := []struct {
+		name        string
+		compression string
+		passphrase  string
+		file        string
+		stored      string
+	}{
+		{"plain trio file", CompressionNone, "", "world(1).xml", "world(1).xml"},
+		{"compressed trio file", CompressionGzip, "", "world(1).bin", "world(1).bin.gz"},
+		{"compressed and encrypted", CompressionGzip, "secret", "world_meta(1).xml", "world_meta(1).xml.gz.enc"},
+		{"save archives stay as they are", CompressionGzip, "", "Mars.save", "Mars.save"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t.TempDir(), "Mars")
+			cfg.Compression = tt.compression
+			cfg.EncryptionPassphrase = tt.passphrase
+			m := newTestManager(t, cfg)
+
+			src := filepath.Join(cfg.BackupDir, tt.file)
+			if err := os.WriteFile(src, content, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			m.mu.Lock()
+			stored, err := m.storeBackupFile(src, filepath.Join(cfg.SafeBackupDir, tt.file))
+			m.mu.Unlock()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filepath.Base(stored) != tt.stored {
+				t.Errorf("stored as %s, want %s", filepath.Base(stored), tt.stored)
+			}
+			if trimStorageSuffixes(stored) != filepath.Join(cfg.SafeBackupDir, tt.file) {
+				t.Errorf("trimStorageSuffixes(%s) = %s", stored, trimStorageSuffixes(stored))
+			}
+
+			r, err := m.openBackupReader(stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Error("read back data differs from the stored file")
+			}
+		})
+	}
+}
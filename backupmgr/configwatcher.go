@@ -0,0 +1,88 @@
+package backupmgr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SteamServerUI/PluginLib"
+)
+
+// configPollInterval is how often SSUI is polled for runfile and settings changes
+const configPollInterval = 15 * time.Second
+
+// WatchConfigChanges polls SSUI for changes to SaveName, RunfileIdentifier and the plugin settings
+// and hot-swaps the global backup manager when any of them changed. It stops once ctx is done and
+// closes the returned channel after a reload it was running has finished.
+// In-flight copies of the previous manager complete before it is replaced, see Shutdown.
+func WatchConfigChanges(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(configPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				checkConfigChanges()
+			}
+		}
+	}()
+	return done
+}
+
+// checkConfigChanges reloads the global backup manager if its config is out of date
+func checkConfigChanges() {
+	initMutex.Lock()
+	manager := GlobalBackupManager
+	initMutex.Unlock()
+	if manager == nil {
+		// No manager runs while the settings are locked, start one once they were fixed
+		if refreshSettings() && lockedSettingsError() == nil {
+			PluginLib.Log(settingsKey+" changed, starting the backup manager", "Info")
+			if err := ReloadBackupManagerFromConfig(); err != nil {
+				PluginLib.Log("Failed to start backup manager after configuration change: "+err.Error(), "Error")
+			}
+		}
+		return
+	}
+
+	saveName, err := getSaveNameFromSSUIRunfile()
+	if err != nil {
+		PluginLib.Log("Config watcher could not read SaveName: "+err.Error(), "Debug")
+		return
+	}
+	runfileIdentifier, err := getRfIdentifierFromSSUIRunfile()
+	if err != nil {
+		PluginLib.Log("Config watcher could not read RunfileIdentifier: "+err.Error(), "Debug")
+		return
+	}
+
+	// The config can change under m.mu, e.g. when the encryption key is rotated
+	manager.mu.Lock()
+	config := manager.config
+	manager.mu.Unlock()
+
+	var changes []string
+	if saveName != config.SaveName {
+		changes = append(changes, fmt.Sprintf("SaveName %q -> %q", config.SaveName, saveName))
+	}
+	if runfileIdentifier != config.RunfileIdentifier {
+		changes = append(changes, fmt.Sprintf("RunfileIdentifier %q -> %q", config.RunfileIdentifier, runfileIdentifier))
+	}
+	if refreshSettings() {
+		changes = append(changes, settingsKey+" changed")
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	PluginLib.Log(fmt.Sprintf("%s Configuration changed (%s), reloading backup manager", config.Identifier, strings.Join(changes, ", ")), "Info")
+	if err := ReloadBackupManagerFromConfig(); err != nil {
+		PluginLib.Log("Failed to reload backup manager after configuration change: "+err.Error(), "Error")
+	}
+}
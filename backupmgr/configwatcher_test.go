@@ -0,0 +1,78 @@
+package backupmgr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testConfig returns a config whose save folders live below dir
+func testConfig(dir, saveName string) BackupConfig {
+	return BackupConfig{
+		WorldName:     saveName,
+		SaveName:      saveName,
+		SaveDir:       filepath.Join(dir, saveName),
+		BackupDir:     filepath.Join(dir, saveName, "autosave"),
+		SafeBackupDir: filepath.Join(dir, saveName, "safebackups"),
+		WaitTime:      200 * time.Millisecond,
+		Identifier:    "test-" + saveName,
+		WatcherMode:   WatcherModePoll,
+		PollInterval:  time.Hour,
+		QuotaPolicy:   QuotaPolicyPrune,
+	}
+}
+
+// waitForLock waits until a started manager owns its safe backup dir
+func waitForLock(t *testing.T, m *BackupManager) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !m.holdsLock() {
+		if time.Now().After(deadline) {
+			t.Fatal("backup manager did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestSaveNameSwitchFinishesPendingCopy checks that a copy pending when SaveName changes is stored
+// in the safe backup dir of the outgoing manager before the new manager takes over
+func TestSaveNameSwitchFinishesPendingCopy(t *testing.T) {
+	dir := t.TempDir()
+	before, after := testConfig(dir, "Mars"), testConfig(dir, "Moon")
+	for _, cfg := range []BackupConfig{before, after} {
+		if err := os.MkdirAll(cfg.BackupDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { ShutdownGlobalBackupManager(5 * time.Second) })
+
+	if err := InitGlobalBackupManager(before); err != nil {
+		t.Fatal(err)
+	}
+	outgoing := GlobalBackupManager
+	waitForLock(t, outgoing)
+
+	autosave := filepath.Join(before.BackupDir, "Mars_autosave.save")
+	if err := os.WriteFile(autosave, []byte("autosave"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outgoing.handleNewBackup(autosave)
+
+	// The copy is still waiting for WaitTime when the manager is swapped
+	if err := InitGlobalBackupManager(after); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := filepath.Join(before.SafeBackupDir, "Mars_autosave.save")
+	data, err := os.ReadFile(stored)
+	if err != nil {
+		t.Fatalf("pending copy was not stored before the swap: %v", err)
+	}
+	if string(data) != "autosave" {
+		t.Errorf("stored copy = %q, want %q", data, "autosave")
+	}
+	if _, err := os.Stat(filepath.Join(after.SafeBackupDir, "Mars_autosave.save")); err == nil {
+		t.Error("copy was stored in the safe backup dir of the new manager")
+	}
+}
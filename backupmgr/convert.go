@@ -0,0 +1,321 @@
+package backupmgr
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/SteamServerUI/PluginLib"
+)
+
+// ErrConversionUnsupported is returned when a backup cannot be converted to the other save format
+var ErrConversionUnsupported = errors.New("backup cannot be converted")
+
+// ConversionReport describes the outcome of converting a backup between the .save and trio formats
+type ConversionReport struct {
+	Source     string   `json:"source"` // ID of the source backup, it is kept
+	SourceType string   `json:"sourceType"`
+	TargetType string   `json:"targetType"`
+	ID         string   `json:"id"` // ID and index of the new backup
+	Index      int      `json:"index"`
+	Files      []string `json:"files"`
+	// Carried lists the source files that made it into the new backup, Dropped those that could not
+	Carried  []string `json:"carried"`
+	Dropped  []string `json:"dropped"`
+	Warnings []string `json:"warnings"`
+}
+
+// trioParts maps the files of a legacy trio to their entry names inside a .save archive
+var trioParts = []struct {
+	entry  string
+	format string // stored file name of the trio part, see nextSnapshotIndex
+}{
+	{"world.xml", "world(%d).xml"},
+	{"world_meta.xml", "world_meta(%d).xml"},
+	{"world.bin", "world(%d).bin"},
+}
+
+// ConvertBackup packages a legacy trio into a .save archive or unpacks a .save into a trio.
+// The source backup is left in place and the catalog records its capture time for the new one. The files are
+// prepared in a temporary directory first so m.mu is only held while storing the result.
+func (m *BackupManager) ConvertBackup(id string) (ConversionReport, error) {
+	if !m.holdsLock() {
+		return ConversionReport{}, errLockNotHeld
+	}
+	group, err := m.GetBackup(id)
+	if err != nil {
+		return ConversionReport{}, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "backup-convert-")
+	if err != nil {
+		return ConversionReport{}, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	report := ConversionReport{Source: id, Carried: []string{}, Dropped: []string{}, Warnings: []string{}}
+	if isSaveFile(group.BinFile) {
+		report.SourceType, report.TargetType = backupTypeSave, backupTypeTrio
+		err = m.convertSaveToTrio(group, tmpDir, &report)
+	} else {
+		report.SourceType, report.TargetType = backupTypeTrio, backupTypeSave
+		err = m.convertTrioToSave(group, tmpDir, &report)
+	}
+	if err != nil {
+		return report, err
+	}
+
+	PluginLib.Log(fmt.Sprintf("%s Converted backup %s from %s to %s as backup %s", m.config.Identifier, id, report.SourceType, report.TargetType, report.ID), "Info")
+	publishEvent(EventBackupConverted, m.config.Identifier, "Backup converted to the other save format", map[string]any{
+		"id": id, "index": group.Index, "newId": report.ID, "newIndex": report.Index, "files": report.Files,
+	})
+	return report, nil
+}
+
+// convertTrioToSave zips the trio into a .save archive with the entry names the game uses
+func (m *BackupManager) convertTrioToSave(group BackupGroup, tmpDir string, report *ConversionReport) error {
+	if err := m.validateWorldFiles(group); err != nil {
+		return err
+	}
+
+	tmpFile := filepath.Join(tmpDir, "converted.save")
+	if err := m.writeTrioArchive(group, tmpFile); err != nil {
+		return err
+	}
+	for _, part := range trioParts {
+		report.Carried = append(report.Carried, part.entry)
+	}
+	report.Warnings = append(report.Warnings,
+		"world.bin holds pre-terrain voxel data that the New Terrain save system does not read, it was kept so the backup can be converted back")
+
+	name := fmt.Sprintf("converted_%s_trio-%d.save", group.ModTime.Format("2006-01-02_15-04-05"), group.Index)
+	return m.storeConverted(group, []string{tmpFile}, func() ([]string, error) {
+		return []string{filepath.Join(m.config.SafeBackupDir, name)}, nil
+	}, report)
+}
+
+// writeTrioArchive writes the trio files of group into a zip archive at dst and verifies it
+func (m *BackupManager) writeTrioArchive(group BackupGroup, dst string) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	sizes := make(map[string]int64)
+	for _, part := range trioParts {
+		src, err := m.openWorldPart(group, part.entry)
+		if err != nil {
+			return fmt.Errorf("failed to open %s of backup %d: %w", part.entry, group.Index, err)
+		}
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: part.entry, Method: zip.Deflate, Modified: group.ModTime})
+		if err == nil {
+			sizes[part.entry], err = io.Copy(fw, src)
+		}
+		src.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write %s to the archive: %w", part.entry, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	// Read the archive back before it replaces anything
+	r, err := zip.OpenReader(dst)
+	if err != nil {
+		return fmt.Errorf("failed to verify converted archive: %w", err)
+	}
+	defer r.Close()
+	if len(r.File) != len(trioParts) {
+		return fmt.Errorf("converted archive has %d entries, expected %d", len(r.File), len(trioParts))
+	}
+	for _, entry := range r.File {
+		if int64(entry.UncompressedSize64) != sizes[entry.Name] {
+			return fmt.Errorf("converted archive entry %s has %d bytes, expected %d", entry.Name, entry.UncompressedSize64, sizes[entry.Name])
+		}
+	}
+	return nil
+}
+
+// convertSaveToTrio extracts world.xml, world_meta.xml and world.bin of a .save into a trio.
+// Only archives that still carry a world.bin, like ones converted from a trio, can be converted.
+func (m *BackupManager) convertSaveToTrio(group BackupGroup, tmpDir string, report *ConversionReport) error {
+	r, closeZip, err := m.openBackupZip(group.BinFile)
+	if err != nil {
+		return err
+	}
+	defer closeZip()
+
+	found := make(map[string]*zip.File)
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name := path.Base(f.Name)
+		if isTrioPart(name) && found[name] == nil {
+			found[name] = f
+			continue
+		}
+		report.Dropped = append(report.Dropped, f.Name)
+	}
+	for _, part := range trioParts {
+		if found[part.entry] == nil {
+			return fmt.Errorf("%w: the archive has no %s, legacy trios need world.xml, world_meta.xml and world.bin", ErrConversionUnsupported, part.entry)
+		}
+	}
+	if err := m.validateWorldFiles(group); err != nil {
+		return err
+	}
+
+	var sources []string
+	for _, part := range trioParts {
+		dst := filepath.Join(tmpDir, part.entry)
+		if err := extractZipEntry(found[part.entry], dst); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", part.entry, err)
+		}
+		sources = append(sources, dst)
+		report.Carried = append(report.Carried, found[part.entry].Name)
+	}
+	if len(report.Dropped) > 0 {
+		report.Warnings = append(report.Warnings, "legacy trios have no place for the dropped entries, keep the .save backup to retain them")
+	}
+
+	return m.storeConverted(group, sources, func() ([]string, error) {
+		index, err := m.nextSnapshotIndex()
+		if err != nil {
+			return nil, err
+		}
+		var destinations []string
+		for _, part := range trioParts {
+			destinations = append(destinations, filepath.Join(m.config.SafeBackupDir, fmt.Sprintf(part.format, index)))
+		}
+		return destinations, nil
+	}, report)
+}
+
+// isTrioPart reports whether a .save entry name is one of the trio files
+func isTrioPart(entry string) bool {
+	for _, part := range trioParts {
+		if part.entry == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// extractZipEntry writes a single zip entry to dst
+func extractZipEntry(f *zip.File, dst string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// openWorldPart opens world.xml, world_meta.xml or world.bin of a backup
+func (m *BackupManager) openWorldPart(group BackupGroup, name string) (io.ReadCloser, error) {
+	if name == "world.bin" && !isSaveFile(group.BinFile) {
+		return m.openBackupReader(group.BinFile)
+	}
+	return m.openWorldFile(group, name)
+}
+
+// validateWorldFiles checks that world.xml and world_meta.xml of a backup parse before converting it
+func (m *BackupManager) validateWorldFiles(group BackupGroup) error {
+	backup, err := m.openBackup(group)
+	if err != nil {
+		return err
+	}
+	defer backup.Close()
+
+	if _, err := backup.worldMeta(); err != nil {
+		return fmt.Errorf("%w: world_meta.xml is invalid: %v", ErrConversionUnsupported, err)
+	}
+	if _, err := backup.summarizeWorld(); err != nil {
+		return fmt.Errorf("%w: world.xml is invalid: %v", ErrConversionUnsupported, err)
+	}
+	return nil
+}
+
+// storeConverted stores the prepared files as a new backup, the catalog keeps the capture time of the source.
+// destinations is called with m.mu held so the chosen names can't be taken concurrently.
+func (m *BackupManager) storeConverted(source BackupGroup, sources []string, destinations func() ([]string, error), report *ConversionReport) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dsts, err := destinations()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.config.SafeBackupDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create safe backup dir: %w", err)
+	}
+	for _, dst := range dsts {
+		for _, candidate := range storedVariants(dst) {
+			if _, err := os.Stat(candidate); err == nil {
+				return fmt.Errorf("%s already exists", filepath.Base(candidate))
+			}
+		}
+	}
+
+	var stored []string
+	for i, src := range sources {
+		storedPath, err := m.storeBackupFile(src, dsts[i])
+		if err != nil {
+			for _, path := range append(stored, storedPath) {
+				if path != "" {
+					os.Remove(path)
+				}
+			}
+			return fmt.Errorf("failed to store converted backup: %w", err)
+		}
+		stored = append(stored, storedPath)
+	}
+	report.Files = stored
+
+	groups, err := m.getBackupGroups()
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if group.BinFile == stored[len(stored)-1] {
+			report.ID, report.Index = group.ID, group.Index
+
+			// The converted backup holds the same world, so it branches off its source
+			sourceKey := m.catalogKey(source)
+			err := m.updateCatalog(func(catalog *backupCatalog) bool {
+				catalog.Backups[group.ID] = CatalogEntry{Parent: sourceKey, CapturedAt: source.ModTime}
+				return true
+			})
+			if err != nil {
+				PluginLib.Log(fmt.Sprintf("%s Failed to record the lineage of converted backup %d: %s", m.config.Identifier, group.Index, err.Error()), "Error")
+			}
+		}
+	}
+
+	m.refreshBackupSetStats()
+	for _, path := range stored {
+		m.replicate(path)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package backupmgr
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+var testTrio = map[string]string{
+	"world.xml":      "<WorldData><AllThings><ThingSaveData><PrefabName>ItemWrench</PrefabName></ThingSaveData></AllThings></WorldData>",
+	"world_meta.xml": "<WorldMetaData><WorldName>Mars</WorldName><GameVersion>0.2</GameVersion></WorldMetaData>",
+	"world.bin":      "voxels",
+}
+
+// writeTrio stores the trio parts of index in the safe backup dir
+func writeTrio(t *testing.T, cfg BackupConfig, index int, parts map[string]string) {
+	t.Helper()
+	for _, part := range trioParts {
+		name := filepath.Join(cfg.SafeBackupDir, fmt.Sprintf(part.format, index))
+		if err := os.WriteFile(name, []byte(parts[part.entry]), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// writeSave stores a .save archive with the given entries in the safe backup dir
+func writeSave(t *testing.T, cfg BackupConfig, name string, entries map[string]string) {
+	t.Helper()
+	f, err := os.Create(filepath.Join(cfg.SafeBackupDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for entry, content := range entries {
+		w, err := zw.Create(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConvertBackup(t *testing.T) {
+	withScreenshot := map[string]string{"screenshot.png": "png"}
+	for name, content := range testTrio {
+		withScreenshot[name] = content
+	}
+	invalidMeta := map[string]string{"world.xml": testTrio["world.xml"], "world_meta.xml": "<WorldMetaData><Open>", "world.bin": "voxels"}
+
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, cfg BackupConfig)
+		id      string
+		target  string
+		dropped []string
+		wantErr error
+	}{
+		{"trio to save", func(t *testing.T, cfg BackupConfig) { writeTrio(t, cfg, 3, testTrio) }, "world(3).bin", backupTypeSave, nil, nil},
+		{"save to trio", func(t *testing.T, cfg BackupConfig) { writeSave(t, cfg, "Mars.save", testTrio) }, "Mars.save", backupTypeTrio, nil, nil},
+		{"save to trio drops other entries", func(t *testing.T, cfg BackupConfig) { writeSave(t, cfg, "Mars.save", withScreenshot) }, "Mars.save", backupTypeTrio, []string{"screenshot.png"}, nil},
+		{"save without world.bin", func(t *testing.T, cfg BackupConfig) {
+			writeSave(t, cfg, "Mars.save", map[string]string{"world.xml": testTrio["world.xml"], "world_meta.xml": testTrio["world_meta.xml"]})
+		}, "Mars.save", "", nil, ErrConversionUnsupported},
+		{"trio with invalid world_meta.xml", func(t *testing.T, cfg BackupConfig) { writeTrio(t, cfg, 3, invalidMeta) }, "world(3).bin", "", nil, ErrConversionUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t.TempDir(), "Mars")
+			m := newTestManager(t, cfg)
+			tt.setup(t, cfg)
+
+			report, err := m.ConvertBackup(tt.id)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ConvertBackup() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.TargetType != tt.target {
+				t.Errorf("target type = %s, want %s", report.TargetType, tt.target)
+			}
+			if !slices.Equal(report.Dropped, tt.dropped) {
+				t.Errorf("dropped = %v, want %v", report.Dropped, tt.dropped)
+			}
+
+			// The source is kept and the new backup holds the same world files
+			if _, err := m.GetBackup(tt.id); err != nil {
+				t.Errorf("source backup is gone: %v", err)
+			}
+			group, err := m.GetBackup(report.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, part := range trioParts {
+				r, err := m.openWorldPart(group, part.entry)
+				if err != nil {
+					t.Fatalf("open %s: %v", part.entry, err)
+				}
+				got, err := io.ReadAll(r)
+				r.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != testTrio[part.entry] {
+					t.Errorf("%s = %q, want %q", part.entry, got, testTrio[part.entry])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package backupmgr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed standard 5-field cron expression (minute hour day-of-month month day-of-week)
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays map[int]bool
+	// Like classic cron, a restricted day-of-month and day-of-week match if either one matches.
+	// Fields starting with * (like */2) count as unrestricted.
+	daysRestricted, weekdaysRestricted bool
+}
+
+// cronSearchYears bounds the search for the next match. The calendar repeats every 400 years,
+// so a schedule that matches at all matches within that time, even one for 29 February on a Monday.
+const cronSearchYears = 400
+
+// maxDaysInMonth is the number of days of each month, February in leap years
+var maxDaysInMonth = [13]int{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// parseCron parses expressions like "*/30 * * * *" or "0 4,16 * * 1-5"
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+
+	var s cronSchedule
+	var err error
+	if s.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid minute field: %w", err)
+	}
+	if s.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid hour field: %w", err)
+	}
+	if s.days, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid day-of-month field: %w", err)
+	}
+	if s.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid month field: %w", err)
+	}
+	if s.weekdays, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid day-of-week field: %w", err)
+	}
+	// Both 0 and 7 mean Sunday
+	if s.weekdays[7] {
+		s.weekdays[0] = true
+	}
+	s.daysRestricted = !strings.HasPrefix(fields[2], "*")
+	s.weekdaysRestricted = !strings.HasPrefix(fields[4], "*")
+	if !s.reachable() {
+		return nil, fmt.Errorf("cron expression %q never matches, none of its months has any of its days", expr)
+	}
+	return &s, nil
+}
+
+// reachable reports whether some date matches the day-of-month, month and day-of-week fields.
+// Every day of a month falls on every weekday over the years, so only the days need to fit a month.
+func (s *cronSchedule) reachable() bool {
+	if s.daysRestricted && s.weekdaysRestricted {
+		return true // any matching weekday will do
+	}
+	for month := range s.months {
+		for day := range s.days {
+			if day <= maxDaysInMonth[month] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseCronField parses a comma separated list of *, n, a-b with an optional /step
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart = part[:i]
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", rangePart)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// next returns the first matching minute strictly after t. Days that don't match are skipped whole.
+func (s *cronSchedule) next(t time.Time) (time.Time, bool) {
+	candidate := t.Truncate(time.Minute).Add(time.Minute)
+	limit := candidate.AddDate(cronSearchYears, 0, 0)
+	for candidate.Before(limit) {
+		if !s.matchesDay(candidate) {
+			year, month, day := candidate.Date()
+			candidate = time.Date(year, month, day+1, 0, 0, 0, 0, candidate.Location())
+			continue
+		}
+		if s.matches(candidate) {
+			return candidate, true
+		}
+		candidate = candidate.Add(time.Minute)
+	}
+	return time.Time{}, false
+}
+
+// matches reports whether t matches the schedule
+func (s *cronSchedule) matches(t time.Time) bool {
+	return s.minutes[t.Minute()] && s.hours[t.Hour()] && s.matchesDay(t)
+}
+
+// matchesDay reports whether the date of t matches the schedule
+func (s *cronSchedule) matchesDay(t time.Time) bool {
+	if !s.months[int(t.Month())] {
+		return false
+	}
+	dayMatch := s.days[t.Day()]
+	weekdayMatch := s.weekdays[int(t.Weekday())]
+	if s.daysRestricted && s.weekdaysRestricted {
+		return dayMatch || weekdayMatch
+	}
+	return dayMatch && weekdayMatch
+}
@@ -0,0 +1,66 @@
+package backupmgr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronRejects(t *testing.T) {
+	tests := []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"0 0 30 2 *",
+		"0 0 31 4,6,9,11 *",
+	}
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := parseCron(expr); err == nil {
+				t.Errorf("parseCron(%q) succeeded, want an error", expr)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// A Sunday afternoon
+	from := time.Date(2026, 10, 18, 14, 7, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 18, 14, 8, 0, 0, time.UTC)},
+		{"*/30 * * * *", time.Date(2026, 10, 18, 14, 30, 0, 0, time.UTC)},
+		{"0 4,16 * * *", time.Date(2026, 10, 18, 16, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Leap days are more than a year away
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either one matches
+		{"0 0 1 * 1", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		// Day fields starting with * are unrestricted, so both have to match
+		{"0 0 */2 * 1", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 2-31/2 * 1", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 */2 * 2", time.Date(2026, 10, 27, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * */5", time.Date(2026, 11, 13, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCron(%q) = %v", tt.expr, err)
+			}
+			got, ok := schedule.next(from)
+			if !ok || !got.Equal(tt.want) {
+				t.Errorf("next() = %v, %v, want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package backupmgr
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Change kinds of diff entries
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// BackupDiff describes what changed from backup A to backup B
+type BackupDiff struct {
+	A       int         `json:"a"`
+	B       int         `json:"b"`
+	Entries []EntryDiff `json:"entries"`
+	Meta    []FieldDiff `json:"meta"`
+	World   WorldDiff   `json:"world"`
+}
+
+// EntryDiff is a file that was added, removed or changed size between two backups
+type EntryDiff struct {
+	Name   string `json:"name"`
+	Change string `json:"change"`
+	SizeA  int64  `json:"sizeA"`
+	SizeB  int64  `json:"sizeB"`
+}
+
+// FieldDiff is a value that was added, removed or changed between two backups
+type FieldDiff struct {
+	Field  string `json:"field"`
+	Change string `json:"change"`
+	A      string `json:"a,omitempty"`
+	B      string `json:"b,omitempty"`
+}
+
+// CountDiff is a collection item type whose count changed between two backups
+type CountDiff struct {
+	Type  string `json:"type"`
+	A     int    `json:"a"`
+	B     int    `json:"b"`
+	Delta int    `json:"delta"`
+}
+
+// WorldDiff is the structural difference between the world.xml files of two backups
+type WorldDiff struct {
+	ElementsA    int64       `json:"elementsA"`
+	ElementsB    int64       `json:"elementsB"`
+	Counts       []CountDiff `json:"counts"`
+	AddedTypes   []string    `json:"addedTypes"`
+	RemovedTypes []string    `json:"removedTypes"`
+	Settings     []FieldDiff `json:"settings"`
+}
+
+// DiffBackups compares the contents of two backups. Only the group lookup holds m.mu,
+// so comparing large saves does not block new backups from being copied.
+func (m *BackupManager) DiffBackups(a, b int) (BackupDiff, error) {
+	groupA, err := m.GetBackupGroup(a)
+	if err != nil {
+		return BackupDiff{}, err
+	}
+	groupB, err := m.GetBackupGroup(b)
+	if err != nil {
+		return BackupDiff{}, err
+	}
+
+	diff := BackupDiff{A: a, B: b}
+
+	// Each backup is opened once, so an encrypted .save is only decrypted as far as it is read
+	backupA, err := m.openBackup(groupA)
+	if err != nil {
+		return diff, fmt.Errorf("failed to open backup %d: %w", a, err)
+	}
+	defer backupA.Close()
+	backupB, err := m.openBackup(groupB)
+	if err != nil {
+		return diff, fmt.Errorf("failed to open backup %d: %w", b, err)
+	}
+	defer backupB.Close()
+
+	entriesA, err := backupA.entries()
+	if err != nil {
+		return diff, fmt.Errorf("failed to list files of backup %d: %w", a, err)
+	}
+	entriesB, err := backupB.entries()
+	if err != nil {
+		return diff, fmt.Errorf("failed to list files of backup %d: %w", b, err)
+	}
+	diff.Entries = diffEntries(entriesA, entriesB)
+
+	metaA, err := backupA.worldMeta()
+	if err != nil {
+		return diff, fmt.Errorf("failed to read world_meta.xml of backup %d: %w", a, err)
+	}
+	metaB, err := backupB.worldMeta()
+	if err != nil {
+		return diff, fmt.Errorf("failed to read world_meta.xml of backup %d: %w", b, err)
+	}
+	diff.Meta = diffFields(metaA, metaB)
+
+	worldA, err := backupA.summarizeWorld()
+	if err != nil {
+		return diff, fmt.Errorf("failed to read world.xml of backup %d: %w", a, err)
+	}
+	worldB, err := backupB.summarizeWorld()
+	if err != nil {
+		return diff, fmt.Errorf("failed to read world.xml of backup %d: %w", b, err)
+	}
+	diff.World = diffWorlds(worldA, worldB)
+
+	return diff, nil
+}
+
+// readWorldMeta reads the fields of a backup's world_meta.xml
+func (m *BackupManager) readWorldMeta(group BackupGroup) (map[string]string, error) {
+	backup, err := m.openBackup(group)
+	if err != nil {
+		return nil, err
+	}
+	defer backup.Close()
+	return backup.worldMeta()
+}
+
+// diffEntries compares two file lists by name and size
+func diffEntries(a, b []BackupEntry) []EntryDiff {
+	sizesA := make(map[string]int64, len(a))
+	for _, entry := range a {
+		sizesA[entry.Name] = entry.Size
+	}
+	sizesB := make(map[string]int64, len(b))
+	for _, entry := range b {
+		sizesB[entry.Name] = entry.Size
+	}
+
+	diffs := []EntryDiff{}
+	for name, sizeA := range sizesA {
+		sizeB, ok := sizesB[name]
+		switch {
+		case !ok:
+			diffs = append(diffs, EntryDiff{Name: name, Change: DiffRemoved, SizeA: sizeA})
+		case sizeA != sizeB:
+			diffs = append(diffs, EntryDiff{Name: name, Change: DiffChanged, SizeA: sizeA, SizeB: sizeB})
+		}
+	}
+	for name, sizeB := range sizesB {
+		if _, ok := sizesA[name]; !ok {
+			diffs = append(diffs, EntryDiff{Name: name, Change: DiffAdded, SizeB: sizeB})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Name < diffs[j].Name })
+	return diffs
+}
+
+// diffFields compares two sets of values by key
+func diffFields(a, b map[string]string) []FieldDiff {
+	diffs := []FieldDiff{}
+	for field, valueA := range a {
+		valueB, ok := b[field]
+		switch {
+		case !ok:
+			diffs = append(diffs, FieldDiff{Field: field, Change: DiffRemoved, A: valueA})
+		case valueA != valueB:
+			diffs = append(diffs, FieldDiff{Field: field, Change: DiffChanged, A: valueA, B: valueB})
+		}
+	}
+	for field, valueB := range b {
+		if _, ok := a[field]; !ok {
+			diffs = append(diffs, FieldDiff{Field: field, Change: DiffAdded, B: valueB})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+	return diffs
+}
+
+// diffWorlds compares two world.xml summaries
+func diffWorlds(a, b WorldSummary) WorldDiff {
+	diff := WorldDiff{
+		ElementsA:    a.Elements,
+		ElementsB:    b.Elements,
+		Counts:       []CountDiff{},
+		AddedTypes:   []string{},
+		RemovedTypes: []string{},
+		Settings:     diffFields(a.Settings, b.Settings),
+	}
+
+	for typ, countA := range a.Counts {
+		countB, ok := b.Counts[typ]
+		if !ok {
+			diff.RemovedTypes = append(diff.RemovedTypes, typ)
+		}
+		if countA != countB {
+			diff.Counts = append(diff.Counts, CountDiff{Type: typ, A: countA, B: countB, Delta: countB - countA})
+		}
+	}
+	for typ, countB := range b.Counts {
+		if _, ok := a.Counts[typ]; !ok {
+			diff.AddedTypes = append(diff.AddedTypes, typ)
+			diff.Counts = append(diff.Counts, CountDiff{Type: typ, B: countB, Delta: countB})
+		}
+	}
+	sort.Strings(diff.AddedTypes)
+	sort.Strings(diff.RemovedTypes)
+	sort.Slice(diff.Counts, func(i, j int) bool { return diff.Counts[i].Type < diff.Counts[j].Type })
+	return diff
+}
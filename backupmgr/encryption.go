@@ -0,0 +1,600 @@
+package backupmgr
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/SteamServerUI/PluginLib"
+)
+
+/*
+Encrypted backups are stored next to plain ones with an additional .enc suffix (e.g. world(3).bin.enc or
+MyWorld.save.enc). The key is derived per file from the configured passphrase or key file contents using
+PBKDF2 and a random salt stored in the file header. The plaintext is sealed in chunks with AES-256-GCM so
+files can be encrypted and decrypted as streams, and read at random offsets without decrypting the rest:
+
+	magic (8 bytes) | salt (16 bytes) | nonce prefix (7 bytes) | chunk 0 | chunk 1 | ...
+
+Every chunk holds up to encryptionChunkSize bytes of plaintext plus the GCM tag. Its nonce is the prefix,
+the chunk number and a flag marking the final chunk, so chunks can't be reordered, dropped or truncated
+unnoticed. Files written before the chunked format (magic SBMENC1) are sealed as a whole and still read.
+*/
+
+const (
+	encryptedSuffix         = ".enc"
+	encryptionMagic         = "SBMENC2\n"
+	encryptionSaltSize      = 16
+	encryptionPrefixSize    = 7
+	encryptionChunkSize     = 64 << 10
+	encryptionKDFIterations = 210000
+	encryptionKeySize       = 32
+
+	// legacyEncryptionMagic marks files sealed as a whole, see openLegacyBackup
+	legacyEncryptionMagic = "SBMENC1\n"
+	// maxLegacyEncryptedSize caps the files read into memory in the legacy format
+	maxLegacyEncryptedSize = 4 << 30
+)
+
+// errManagerRetired is returned for backups stored by a manager that is being replaced after a key rotation
+var errManagerRetired = errors.New("the backup manager is being replaced after a key rotation")
+
+// errNoEncryptionKey is returned when an encrypted backup is read without a configured key
+var errNoEncryptionKey = errors.New("backup is encrypted but no encryption passphrase or key file is configured")
+
+// encryptionSecret returns the configured secret, or nil if encryption is disabled.
+// A key file takes precedence over a passphrase.
+func (c BackupConfig) encryptionSecret() ([]byte, error) {
+	if c.EncryptionKeyFile != "" {
+		data, err := os.ReadFile(c.EncryptionKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key file %s: %w", c.EncryptionKeyFile, err)
+		}
+		secret := bytes.TrimSpace(data)
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("encryption key file %s is empty", c.EncryptionKeyFile)
+		}
+		return secret, nil
+	}
+	if c.EncryptionPassphrase != "" {
+		return []byte(c.EncryptionPassphrase), nil
+	}
+	return nil, nil
+}
+
+// encryptionEnabled reports whether new backups should be stored encrypted
+func (c BackupConfig) encryptionEnabled() bool {
+	return c.EncryptionKeyFile != "" || c.EncryptionPassphrase != ""
+}
+
+// isEncryptedFile reports whether a backup file is stored encrypted
+func isEncryptedFile(path string) bool {
+	return strings.HasSuffix(path, encryptedSuffix)
+}
+
+// trimEncryptedSuffix returns the file name as it was before encryption
+func trimEncryptedSuffix(path string) string {
+	return strings.TrimSuffix(path, encryptedSuffix)
+}
+
+// isSaveFile reports whether a (possibly encrypted) backup file is a .save archive
+func isSaveFile(path string) bool {
+	return strings.HasSuffix(trimStorageSuffixes(path), ".save")
+}
+
+// newFileCipher derives the AEAD for a single file from the secret and salt
+func newFileCipher(secret, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, string(secret), salt, encryptionKDFIterations, encryptionKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns the nonce of a chunk: the file's nonce prefix, the chunk number and the final chunk flag
+func chunkNonce(prefix []byte, chunk uint32, last bool) []byte {
+	nonce := make([]byte, 0, encryptionPrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, chunk)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// encryptStream reads plaintext from src until EOF and writes it to dst encrypted with the given secret
+func encryptStream(dst io.Writer, src io.Reader, secret []byte) error {
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	prefix := make([]byte, encryptionPrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	aead, err := newFileCipher(secret, salt)
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, 0, len(encryptionMagic)+len(salt)+len(prefix))
+	header = append(header, encryptionMagic...)
+	header = append(header, salt...)
+	header = append(header, prefix...)
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+
+	plain := make([]byte, encryptionChunkSize)
+	sealed := make([]byte, 0, encryptionChunkSize+aead.Overhead())
+	for chunk := uint32(0); ; chunk++ {
+		// A full chunk is never marked final, data ending on a chunk boundary gets an empty final chunk
+		n, err := io.ReadFull(src, plain)
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return err
+		}
+		if chunk == math.MaxUint32 && !last {
+			return fmt.Errorf("file is too large to encrypt")
+		}
+
+		// The header is authenticated as additional data so chunks can't be swapped between files
+		sealed = aead.Seal(sealed[:0], chunkNonce(prefix, chunk, last), plain[:n], header)
+		if _, err := dst.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// chunkedReader decrypts the chunks of an encrypted file on demand. It caches the last chunk
+// it decrypted, so sequential reads decrypt every chunk once.
+type chunkedReader struct {
+	src    io.ReaderAt
+	aead   cipher.AEAD
+	header []byte
+	prefix []byte
+	body   int64 // size of the sealed chunks
+	chunks int64
+	size   int64 // plaintext size
+
+	mu     sync.Mutex
+	cached int64 // number of the chunk held in plain, -1 if none
+	plain  []byte
+	sealed []byte
+}
+
+// newDecryptingReader returns a reader of the plaintext of an encrypted file of the given size and the plaintext size
+func newDecryptingReader(src io.ReaderAt, fileSize int64, secret []byte) (io.ReaderAt, int64, error) {
+	header := make([]byte, len(encryptionMagic)+encryptionSaltSize+encryptionPrefixSize)
+	if _, err := src.ReadAt(header[:len(encryptionMagic)], 0); err != nil {
+		return nil, 0, fmt.Errorf("not an encrypted backup file: %w", err)
+	}
+	switch string(header[:len(encryptionMagic)]) {
+	case encryptionMagic:
+	case legacyEncryptionMagic:
+		return openLegacyBackup(src, fileSize, secret)
+	default:
+		return nil, 0, fmt.Errorf("not an encrypted backup file")
+	}
+	if n, err := src.ReadAt(header, 0); n < len(header) {
+		return nil, 0, fmt.Errorf("encrypted backup file is truncated: %w", err)
+	}
+
+	salt := header[len(encryptionMagic) : len(encryptionMagic)+encryptionSaltSize]
+	aead, err := newFileCipher(secret, salt)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	body := fileSize - int64(len(header))
+	sealedChunk := int64(encryptionChunkSize + aead.Overhead())
+	chunks := (body + sealedChunk - 1) / sealedChunk
+	if chunks == 0 || body-(chunks-1)*sealedChunk < int64(aead.Overhead()) {
+		return nil, 0, fmt.Errorf("encrypted backup file is truncated")
+	}
+
+	r := &chunkedReader{
+		src:    src,
+		aead:   aead,
+		header: header,
+		prefix: header[len(header)-encryptionPrefixSize:],
+		body:   body,
+		chunks: chunks,
+		size:   body - chunks*int64(aead.Overhead()),
+		cached: -1,
+		sealed: make([]byte, sealedChunk),
+	}
+	// Opening the final chunk up front reports a wrong key or a truncated file before anything is read
+	if _, err := r.chunk(chunks - 1); err != nil {
+		return nil, 0, err
+	}
+	return r, r.size, nil
+}
+
+// ReadAt implements io.ReaderAt
+func (c *chunkedReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= c.size {
+			return n, io.EOF
+		}
+		chunk := pos / encryptionChunkSize
+		plain, err := c.chunk(chunk)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], plain[pos-chunk*encryptionChunkSize:])
+	}
+	return n, nil
+}
+
+// chunk returns the plaintext of a chunk. The caller must hold c.mu.
+func (c *chunkedReader) chunk(chunk int64) ([]byte, error) {
+	if chunk == c.cached {
+		return c.plain, nil
+	}
+
+	sealedChunk := int64(len(c.sealed))
+	offset := chunk * sealedChunk
+	sealed := c.sealed[:min(sealedChunk, c.body-offset)]
+	if n, err := c.src.ReadAt(sealed, int64(len(c.header))+offset); n < len(sealed) {
+		return nil, fmt.Errorf("failed to read encrypted backup: %w", err)
+	}
+
+	last := chunk == c.chunks-1
+	plain, err := c.aead.Open(c.plain[:0], chunkNonce(c.prefix, uint32(chunk), last), sealed, c.header)
+	if err != nil {
+		c.cached = -1
+		return nil, fmt.Errorf("failed to decrypt backup (wrong key or corrupted file): %w", err)
+	}
+	c.plain, c.cached = plain, chunk
+	return plain, nil
+}
+
+// openLegacyBackup decrypts a file sealed as a whole by earlier versions. It has to be read into memory.
+func openLegacyBackup(src io.ReaderAt, fileSize int64, secret []byte) (io.ReaderAt, int64, error) {
+	if fileSize > maxLegacyEncryptedSize {
+		return nil, 0, fmt.Errorf("encrypted backup file is too large for the legacy format, rotate the key to convert it")
+	}
+	data := make([]byte, fileSize)
+	if n, err := src.ReadAt(data, 0); n < len(data) {
+		return nil, 0, fmt.Errorf("failed to read encrypted backup: %w", err)
+	}
+	if len(data) < len(legacyEncryptionMagic)+encryptionSaltSize {
+		return nil, 0, fmt.Errorf("encrypted backup file is truncated")
+	}
+	salt := data[len(legacyEncryptionMagic) : len(legacyEncryptionMagic)+encryptionSaltSize]
+	aead, err := newFileCipher(secret, salt)
+	if err != nil {
+		return nil, 0, err
+	}
+	headerLen := len(legacyEncryptionMagic) + encryptionSaltSize + aead.NonceSize()
+	if len(data) < headerLen {
+		return nil, 0, fmt.Errorf("encrypted backup file is truncated")
+	}
+	header := data[:headerLen]
+	nonce := data[headerLen-aead.NonceSize() : headerLen]
+	plaintext, err := aead.Open(nil, nonce, data[headerLen:], header)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decrypt backup (wrong key or corrupted file): %w", err)
+	}
+	return bytes.NewReader(plaintext), int64(len(plaintext)), nil
+}
+
+// writeStream writes everything read from src to path and flushes it to disk. With a secret the
+// data is encrypted on the way.
+func writeStream(path string, src io.Reader, secret []byte) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if secret != nil {
+		err = encryptStream(f, src, secret)
+	} else {
+		_, err = io.Copy(f, src)
+	}
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// encryptFile copies src to dst, encrypting it with the given secret
+func encryptFile(src, dst string, secret []byte) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeStream(dst, in, secret)
+}
+
+// writeFileSync writes data to path and flushes it to disk
+func writeFileSync(path string, data []byte) error {
+	return writeStream(path, bytes.NewReader(data), nil)
+}
+
+// backupFile is the plaintext of a stored backup file. Reads are served from disk,
+// encrypted files are decrypted chunk by chunk as they are read.
+type backupFile struct {
+	*io.SectionReader
+	file *os.File
+}
+
+// Close closes the underlying file
+func (f *backupFile) Close() error {
+	return f.file.Close()
+}
+
+// openBackupFile opens a stored backup file for reading its plaintext, decrypting it if needed
+func (m *BackupManager) openBackupFile(path string) (*backupFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !isEncryptedFile(path) {
+		return &backupFile{SectionReader: io.NewSectionReader(f, 0, info.Size()), file: f}, nil
+	}
+
+	secret, err := m.config.encryptionSecret()
+	if err == nil && secret == nil {
+		err = errNoEncryptionKey
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	plain, size, err := newDecryptingReader(f, info.Size(), secret)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return &backupFile{SectionReader: io.NewSectionReader(plain, 0, size), file: f}, nil
+}
+
+// openBackupReader opens a backup file for reading, decrypting and decompressing it if needed.
+// Unlike openBackupFile it also reads compressed files, see compression.go.
+func (m *BackupManager) openBackupReader(path string) (io.ReadCloser, error) {
+	f, err := m.openBackupFile(path)
+	if err != nil || !isCompressedFile(path) {
+		return f, err
+	}
+	return newDecompressingReader(f)
+}
+
+// storeBackupFile copies a file into the safe backup location, compressing and encrypting it as
+// configured and enforcing the storage limits first. It returns the path the file was actually
+// written to. The caller must hold m.mu.
+func (m *BackupManager) storeBackupFile(src, dst string) (string, error) {
+	if !m.holdsLock() {
+		return "", errLockNotHeld
+	}
+	if m.retired {
+		return "", errManagerRetired
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return "", err
+	}
+	if err := m.ensureSpace(info.Size()); err != nil {
+		return "", err
+	}
+
+	compress := m.config.shouldCompress(dst)
+	if !m.config.encryptionEnabled() && !compress {
+		return dst, copyFile(src, dst)
+	}
+	secret, err := m.config.encryptionSecret()
+	if err != nil {
+		return "", err
+	}
+	if !compress {
+		dst += encryptedSuffix
+		return dst, encryptFile(src, dst, secret)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	compressed := compressStream(in)
+	defer compressed.Close()
+	dst += compressedSuffix
+	if secret != nil {
+		dst += encryptedSuffix
+	}
+	return dst, writeStream(dst, compressed, secret)
+}
+
+// openBackupZip opens a .save backup as a zip archive. Encrypted archives are decrypted as they are read.
+// The returned close function must be called once the reader is no longer used.
+func (m *BackupManager) openBackupZip(path string) (*zip.Reader, func() error, error) {
+	f, err := m.openBackupFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := zip.NewReader(f, f.Size())
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return r, f.Close, nil
+}
+
+// ErrInvalidKey is returned by RotateEncryptionKey when the new passphrase or key file can't be used
+var ErrInvalidKey = errors.New("invalid encryption key")
+
+// RotateEncryptionKey re-encrypts every stored backup with a new passphrase or key file, persists
+// the new key in the settings and replaces the global backup manager with one using it. Plain backups
+// are encrypted as well; passing neither a passphrase nor a key file decrypts all backups. All files
+// are rewritten to temporary files first and the old files are only removed once the new key is
+// saved, any failure before that restores the previous backup set.
+func (m *BackupManager) RotateEncryptionKey(newPassphrase, newKeyFile string) (int, error) {
+	count, newConfig, err := m.rotateEncryptionKey(newPassphrase, newKeyFile)
+	if err != nil {
+		return 0, err
+	}
+	// m.config is read without m.mu in many places, so the new key comes with a new manager
+	if err := InitGlobalBackupManager(newConfig); err != nil {
+		return count, fmt.Errorf("re-encrypted %d backup files but failed to reload the backup manager: %w", count, err)
+	}
+	return count, nil
+}
+
+// rotateEncryptionKey rewrites the backups for RotateEncryptionKey and returns the config to reload with.
+// The SafeBackupDir lock is held throughout so no other instance writes with the old key meanwhile.
+func (m *BackupManager) rotateEncryptionKey(newPassphrase, newKeyFile string) (int, BackupConfig, error) {
+	release, err := m.borrowLock()
+	if err != nil {
+		return 0, BackupConfig{}, err
+	}
+	defer release()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	settings := GetSettings()
+	settings.Encryption = EncryptionSettings{Passphrase: newPassphrase, KeyFile: newKeyFile}
+	if err := settings.Validate(); err != nil {
+		return 0, BackupConfig{}, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	newConfig := m.config
+	newConfig.EncryptionPassphrase = newPassphrase
+	newConfig.EncryptionKeyFile = newKeyFile
+	newSecret, err := newConfig.encryptionSecret()
+	if err != nil {
+		return 0, BackupConfig{}, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	var files []string
+	err = filepath.WalkDir(m.config.SafeBackupDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isValidBackupFile(trimStorageSuffixes(d.Name())) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, BackupConfig{}, fmt.Errorf("failed to walk safe backup dir: %w", err)
+	}
+
+	type rotation struct {
+		oldPath   string
+		newPath   string
+		tmpPath   string
+		asidePath string
+		swapped   bool
+	}
+	var rotations []*rotation
+	// rollback puts the old files back in place and removes everything written so far
+	rollback := func() {
+		for _, r := range rotations {
+			if r.swapped {
+				if err := os.Remove(r.newPath); err != nil && !os.IsNotExist(err) {
+					PluginLib.Log(fmt.Sprintf("%s Failed to remove %s while rolling back the key rotation: %s", m.config.Identifier, r.newPath, err.Error()), "Error")
+				}
+			}
+			if _, err := os.Stat(r.asidePath); err == nil {
+				if err := os.Rename(r.asidePath, r.oldPath); err != nil {
+					PluginLib.Log(fmt.Sprintf("%s Failed to restore %s while rolling back the key rotation, it was kept as %s: %s", m.config.Identifier, r.oldPath, r.asidePath, err.Error()), "Error")
+				}
+			}
+			os.Remove(r.tmpPath)
+		}
+	}
+
+	for _, path := range files {
+		newPath := trimEncryptedSuffix(path)
+		if newSecret != nil {
+			newPath += encryptedSuffix
+		}
+		r := &rotation{oldPath: path, newPath: newPath, tmpPath: newPath + ".rotating", asidePath: path + ".rotated"}
+		rotations = append(rotations, r)
+
+		// Compressed files stay compressed, only their encryption changes
+		src, err := m.openBackupFile(path)
+		if err != nil {
+			rollback()
+			return 0, BackupConfig{}, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		err = writeStream(r.tmpPath, src, newSecret)
+		src.Close()
+		if err != nil {
+			rollback()
+			return 0, BackupConfig{}, fmt.Errorf("failed to write %s: %w", r.tmpPath, err)
+		}
+	}
+
+	// Move every old file aside before its replacement takes its place, so they can be put back
+	for _, r := range rotations {
+		if err := os.Rename(r.oldPath, r.asidePath); err != nil {
+			rollback()
+			return 0, BackupConfig{}, fmt.Errorf("failed to replace %s: %w", r.oldPath, err)
+		}
+		if err := os.Rename(r.tmpPath, r.newPath); err != nil {
+			rollback()
+			return 0, BackupConfig{}, fmt.Errorf("failed to replace %s: %w", r.newPath, err)
+		}
+		r.swapped = true
+	}
+
+	// The backups can only be read with the new key from here on, so it has to be saved before reporting success
+	if err := storeSettings(settings); err != nil {
+		rollback()
+		return 0, BackupConfig{}, fmt.Errorf("failed to persist the new key, the backups were left unchanged: %w", err)
+	}
+
+	for _, r := range rotations {
+		if err := os.Remove(r.asidePath); err != nil {
+			PluginLib.Log(fmt.Sprintf("%s Failed to remove %s after key rotation: %s", m.config.Identifier, r.asidePath, err.Error()), "Error")
+		}
+		// Cached thumbnails are plaintext or encrypted with the old key, they are rebuilt on demand
+		os.Remove(thumbnailCachePath(r.oldPath))
+	}
+
+	// Copies still pending until the manager is replaced would use the old key, the next manager reconciles them
+	m.retired = true
+	PluginLib.Log(fmt.Sprintf("%s Re-encrypted %d backup files with the new key", m.config.Identifier, len(rotations)), "Info")
+	return len(rotations), newConfig, nil
+}
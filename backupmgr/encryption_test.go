@@ -0,0 +1,86 @@
+package backupmgr
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+// encryptBytes encrypts plaintext with secret
+func encryptBytes(t *testing.T, plaintext, secret []byte) []byte {
+	t.Helper()
+	var sealed bytes.Buffer
+	if err := encryptStream(&sealed, bytes.NewReader(plaintext), secret); err != nil {
+		t.Fatal(err)
+	}
+	return sealed.Bytes()
+}
+
+// decryptBytes decrypts a whole encrypted file
+func decryptBytes(sealed, secret []byte) ([]byte, error) {
+	plain, size, err := newDecryptingReader(bytes.NewReader(sealed), int64(len(sealed)), secret)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(io.NewSectionReader(plain, 0, size))
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	secret := []byte("correct horse battery staple")
+	tests := []struct {
+		name string
+		size int
+	}{
+		{"empty", 0},
+		{"single byte", 1},
+		{"just below a chunk", encryptionChunkSize - 1},
+		{"one chunk", encryptionChunkSize},
+		{"just above a chunk", encryptionChunkSize + 1},
+		{"several chunks", 3*encryptionChunkSize + 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext := make([]byte, tt.size)
+			rand.Read(plaintext)
+
+			sealed := encryptBytes(t, plaintext, secret)
+			got, err := decryptBytes(sealed, secret)
+			if err != nil {
+				t.Fatalf("decrypt: %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Fatal("decrypted data differs from the plaintext")
+			}
+			if _, err := decryptBytes(sealed, []byte("wrong key")); err == nil {
+				t.Error("decrypting with the wrong key succeeded")
+			}
+		})
+	}
+}
+
+func TestEncryptionRejectsTruncatedCiphertext(t *testing.T) {
+	secret := []byte("correct horse battery staple")
+	plaintext := make([]byte, 2*encryptionChunkSize+100)
+	rand.Read(plaintext)
+	sealed := encryptBytes(t, plaintext, secret)
+	header := len(encryptionMagic) + encryptionSaltSize + encryptionPrefixSize
+
+	tests := []struct {
+		name   string
+		length int
+	}{
+		{"header only", header},
+		{"inside the header", header - 3},
+		{"last chunk dropped", len(sealed) - 100 - 16},
+		{"last byte missing", len(sealed) - 1},
+		{"inside a chunk", header + encryptionChunkSize/2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := decryptBytes(sealed[:tt.length], secret); err == nil {
+				t.Errorf("decrypting %d of %d bytes succeeded with %d bytes of plaintext", tt.length, len(sealed), len(got))
+			}
+		})
+	}
+}
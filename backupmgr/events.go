@@ -0,0 +1,90 @@
+package backupmgr
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published by the backup manager
+const (
+	EventBackupDetected  = "backup.detected"
+	EventCopyStarted     = "backup.copy_started"
+	EventCopyFinished    = "backup.copied"
+	EventCopyFailed      = "backup.copy_failed"
+	EventSnapshotCreated = "backup.snapshot"
+	EventBackupConverted = "backup.converted"
+	EventBackupImported  = "backup.imported"
+	EventQuotaExceeded   = "backup.quota_exceeded"
+	EventRestoreStarted  = "restore.started"
+	EventRestoreProgress = "restore.progress"
+	EventRestoreFinished = "restore.finished"
+	EventRestoreFailed   = "restore.failed"
+	EventWatcherError    = "watcher.error"
+	EventManagerReloaded = "manager.reloaded"
+)
+
+// Event describes a single backup manager activity
+type Event struct {
+	Type       string         `json:"type"`
+	Identifier string         `json:"identifier,omitempty"`
+	Message    string         `json:"message"`
+	Data       map[string]any `json:"data,omitempty"`
+	Time       time.Time      `json:"time"`
+}
+
+// eventBroker fans out events to all subscribers. It outlives individual
+// manager instances so subscribers keep receiving events across reloads.
+type eventBroker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+// eventBufferSize is the number of events buffered per subscriber before new events are dropped
+const eventBufferSize = 64
+
+var events = &eventBroker{subscribers: make(map[chan Event]struct{})}
+
+// subscribe registers a new subscriber and returns its event channel
+func (b *eventBroker) subscribe() chan Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, eventBufferSize)
+	b.subscribers[ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe removes a subscriber and closes its channel
+func (b *eventBroker) unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// publish delivers an event to all subscribers without blocking; slow subscribers miss events
+func (b *eventBroker) publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// publishEvent creates and publishes an event
+func publishEvent(eventType, identifier, message string, data map[string]any) {
+	events.publish(Event{
+		Type:       eventType,
+		Identifier: identifier,
+		Message:    message,
+		Data:       data,
+		Time:       time.Now(),
+	})
+}
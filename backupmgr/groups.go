@@ -19,12 +19,6 @@ func (m *BackupManager) getBackupGroups() ([]BackupGroup, error) {
 		}
 		return nil
 	})
-	// list all files in the safe backup dir to console for debugging
-	for _, file := range files {
-		fmt.Println(file.Name())
-	}
-	// print the dir to console for debugging
-	fmt.Println(m.config.SafeBackupDir)
 	if err != nil {
 		// if the error contains no such file or directory, return nil but return a custom string intsted 	of the error
 		if strings.Contains(err.Error(), "no such file or directory") || strings.Contains(err.Error(), "The system cannot find the file specified") {
@@ -36,19 +30,20 @@ func (m *BackupManager) getBackupGroups() ([]BackupGroup, error) {
 	groups := make(map[int]BackupGroup)
 
 	for _, file := range files {
-		filename := file.Name()
+		// Compressed and encrypted files are classified by their original name
+		filename := trimStorageSuffixes(file.Name())
 		if !isValidBackupFile(filename) {
 			continue
 		}
 
-		fullPath := filepath.Join(m.config.SafeBackupDir, filename)
+		fullPath := filepath.Join(m.config.SafeBackupDir, file.Name())
 		info, err := file.Info()
 		if err != nil {
 			continue
 		}
 
 		// Parse index or assign synthetic index for .save files
-		index := parseBackupIndex(filename, info.ModTime(), files)
+		index := parseBackupIndex(file.Name(), info.ModTime(), files)
 		if index == -1 {
 			continue
 		}
@@ -76,7 +71,8 @@ func (m *BackupManager) getBackupGroups() ([]BackupGroup, error) {
 	var result []BackupGroup
 	for _, group := range groups {
 		// Include both old-style groups (all three files) and .save-based groups (just BinFile)
-		if (group.BinFile != "" && group.XMLFile != "" && group.MetaFile != "") || (group.BinFile != "" && strings.HasSuffix(group.BinFile, ".save")) {
+		if (group.BinFile != "" && group.XMLFile != "" && group.MetaFile != "") || (group.BinFile != "" && isSaveFile(group.BinFile)) {
+			group.ID = m.catalogKey(group)
 			result = append(result, group)
 		}
 	}
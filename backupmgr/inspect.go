@@ -0,0 +1,77 @@
+package backupmgr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BackupInspection holds statistics about the world stored in a backup
+type BackupInspection struct {
+	ID      string        `json:"id"`
+	Index   int           `json:"index"`
+	Type    string        `json:"type"`
+	Entries []BackupEntry `json:"entries"`
+
+	// Things counts every item of the Things collection, Structures the ones with a Structure prefab
+	Things      int `json:"things"`
+	Structures  int `json:"structures"`
+	Atmospheres int `json:"atmospheres"`
+	Rooms       int `json:"rooms"`
+	// Players counts entries of player collections, Characters the character things in the world
+	Players    int `json:"players"`
+	Characters int `json:"characters"`
+
+	World WorldSummary `json:"world"`
+}
+
+// InspectBackup streams the world.xml of a backup and counts its contents without restoring it.
+// Like DiffBackups it only holds m.mu for the group lookup.
+func (m *BackupManager) InspectBackup(id string) (BackupInspection, error) {
+	group, err := m.GetBackup(id)
+	if err != nil {
+		return BackupInspection{}, err
+	}
+
+	inspection := BackupInspection{ID: id, Index: group.Index, Type: backupTypeTrio}
+	if isSaveFile(group.BinFile) {
+		inspection.Type = backupTypeSave
+	}
+
+	backup, err := m.openBackup(group)
+	if err != nil {
+		return inspection, fmt.Errorf("failed to open backup %s: %w", id, err)
+	}
+	defer backup.Close()
+
+	inspection.Entries, err = backup.entries()
+	if err != nil {
+		return inspection, fmt.Errorf("failed to list files of backup %s: %w", id, err)
+	}
+	inspection.World, err = backup.summarizeWorld()
+	if err != nil {
+		return inspection, fmt.Errorf("failed to read world.xml of backup %s: %w", id, err)
+	}
+
+	for key, count := range inspection.World.Counts {
+		section, _, _ := strings.Cut(key, "/")
+		switch {
+		case section == "Things":
+			inspection.Things += count
+		case strings.Contains(section, "Atmosphere"):
+			inspection.Atmospheres += count
+		case strings.Contains(section, "Room"):
+			inspection.Rooms += count
+		case strings.Contains(section, "Player"):
+			inspection.Players += count
+		}
+	}
+	for prefab, count := range inspection.World.Prefabs {
+		switch {
+		case strings.HasPrefix(prefab, "Structure"):
+			inspection.Structures += count
+		case strings.HasPrefix(prefab, "Character"):
+			inspection.Characters += count
+		}
+	}
+	return inspection, nil
+}
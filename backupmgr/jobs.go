@@ -0,0 +1,315 @@
+package backupmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SteamServerUI/PluginLib"
+	"github.com/google/uuid"
+)
+
+// JobState describes where a background job is in its lifecycle
+type JobState string
+
+const (
+	JobQueued    JobState = "queued"
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+	JobCanceled  JobState = "canceled"
+)
+
+// JobTypeRestore is the type of jobs that restore a backup into the live save
+const JobTypeRestore = "restore"
+
+// Job is a snapshot of a background job as exposed over the API
+type Job struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	BackupIndex int    `json:"backupIndex"`
+	// BackupID pins the backup when the job is queued so shifting indexes can't make it restore
+	// a different backup, see BackupGroup.ID
+	BackupID    string     `json:"backupId"`
+	RequestedBy string     `json:"requestedBy"`
+	State       JobState   `json:"state"`
+	BytesDone   int64      `json:"bytesDone"`
+	BytesTotal  int64      `json:"bytesTotal"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+}
+
+// finished reports whether the job reached a terminal state
+func (j Job) finished() bool {
+	return j.State == JobSucceeded || j.State == JobFailed || j.State == JobCanceled
+}
+
+// trackedJob is a job together with the means to cancel and wait for it
+type trackedJob struct {
+	Job
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{} // closed once the job finished
+}
+
+var (
+	// ErrJobNotFound is returned when no job with the given ID is known
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished is returned when canceling a job that already finished
+	ErrJobFinished = errors.New("job already finished")
+	// errJobQueueFull is returned when too many jobs are waiting to run
+	errJobQueueFull = errors.New("too many jobs queued, try again later")
+)
+
+const (
+	// jobQueueSize is the number of jobs that may wait for the worker at once
+	jobQueueSize = 16
+	// maxFinishedJobs is the number of finished jobs kept around for status queries
+	maxFinishedJobs = 50
+)
+
+// jobRegistry keeps track of all jobs. Like the event broker it outlives manager instances,
+// so job IDs stay valid across reloads.
+type jobRegistry struct {
+	mu    sync.Mutex
+	jobs  map[string]*trackedJob
+	order []string
+}
+
+var jobs = &jobRegistry{jobs: make(map[string]*trackedJob)}
+
+// add registers a new job and forgets the oldest finished jobs beyond maxFinishedJobs
+func (r *jobRegistry) add(job *trackedJob) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.jobs[job.ID] = job
+	r.order = append(r.order, job.ID)
+
+	finished := 0
+	for _, id := range r.order {
+		if r.jobs[id].finished() {
+			finished++
+		}
+	}
+	kept := r.order[:0]
+	for _, id := range r.order {
+		if finished > maxFinishedJobs && r.jobs[id].finished() {
+			delete(r.jobs, id)
+			finished--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	r.order = kept
+}
+
+// get returns a snapshot of the job with the given ID
+func (r *jobRegistry) get(id string) (Job, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return job.Job, true
+}
+
+// update applies fn to the job with the given ID while holding the registry lock
+func (r *jobRegistry) update(id string, fn func(job *trackedJob)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if job, ok := r.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+// finish moves a job into a terminal state
+func (r *jobRegistry) finish(id string, state JobState, err error) {
+	r.update(id, func(job *trackedJob) {
+		now := time.Now()
+		job.State = state
+		job.FinishedAt = &now
+		if err != nil {
+			job.Error = err.Error()
+		}
+		job.cancel()
+		select {
+		case <-job.done:
+		default:
+			close(job.done)
+		}
+	})
+}
+
+// wait blocks until the job with the given ID finished or ctx is done and returns its final state
+func (r *jobRegistry) wait(ctx context.Context, id string) (Job, error) {
+	r.mu.Lock()
+	job, ok := r.jobs[id]
+	r.mu.Unlock()
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+
+	select {
+	case <-job.done:
+	case <-ctx.Done():
+		return Job{}, ctx.Err()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return job.Job, nil
+}
+
+// GetJob returns the job with the given ID
+func GetJob(id string) (Job, error) {
+	job, ok := jobs.get(id)
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// CancelJob requests cancellation of a queued or running job. Running restores roll back
+// and the job ends up canceled once the worker notices.
+func CancelJob(id string) (Job, error) {
+	var snapshot Job
+	found := false
+	jobs.update(id, func(job *trackedJob) {
+		found = true
+		if !job.finished() {
+			job.cancel()
+		}
+		snapshot = job.Job
+	})
+	if !found {
+		return Job{}, ErrJobNotFound
+	}
+	if snapshot.finished() {
+		return snapshot, ErrJobFinished
+	}
+	return snapshot, nil
+}
+
+// cancelAllJobs requests cancellation of every queued or running job
+func cancelAllJobs() {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+
+	for _, job := range jobs.jobs {
+		if !job.finished() {
+			job.cancel()
+		}
+	}
+}
+
+// EnqueueRestore queues a restore of group on behalf of requestedBy and returns the new job.
+// Restores run one at a time on the manager's job worker.
+func (m *BackupManager) EnqueueRestore(group BackupGroup, requestedBy string) (Job, error) {
+	// Jobs are not tied to the manager context so a reload lets a running restore finish
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &trackedJob{
+		Job: Job{
+			ID:          uuid.New().String(),
+			Type:        JobTypeRestore,
+			BackupIndex: group.Index,
+			BackupID:    group.ID,
+			RequestedBy: requestedBy,
+			State:       JobQueued,
+			CreatedAt:   time.Now(),
+		},
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	// Register before queueing so the worker always finds the job it picks up
+	jobs.add(job)
+	select {
+	case m.jobQueue <- job:
+	default:
+		jobs.finish(job.ID, JobFailed, errJobQueueFull)
+		return Job{}, errJobQueueFull
+	}
+	PluginLib.Log(fmt.Sprintf("%s Queued restore of backup %s as job %s", m.config.Identifier, group.ID, job.ID), "Info")
+	return job.Job, nil
+}
+
+// runJobs executes queued jobs one after another until the manager shuts down.
+// Jobs still waiting in the queue at that point are canceled.
+func (m *BackupManager) runJobs() {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			for {
+				select {
+				case job := <-m.jobQueue:
+					jobs.finish(job.ID, JobCanceled, errors.New("backup manager was shut down"))
+				default:
+					return
+				}
+			}
+		case job := <-m.jobQueue:
+			m.runRestoreJob(job)
+		}
+	}
+}
+
+// runRestoreJob runs a single restore job, keeping its progress and state up to date
+func (m *BackupManager) runRestoreJob(job *trackedJob) {
+	if job.ctx.Err() != nil {
+		jobs.finish(job.ID, JobCanceled, nil)
+		return
+	}
+
+	jobs.update(job.ID, func(j *trackedJob) {
+		now := time.Now()
+		j.State = JobRunning
+		j.StartedAt = &now
+	})
+
+	index := job.BackupIndex
+	data := map[string]any{"index": index, "job": job.ID}
+	publishEvent(EventRestoreStarted, m.config.Identifier, fmt.Sprintf("Restoring backup %d", index), data)
+
+	start := time.Now()
+	err := m.restoreBackup(job.ctx, job.BackupID, func(done, total int64) {
+		jobs.update(job.ID, func(j *trackedJob) {
+			j.BytesDone = done
+			j.BytesTotal = total
+		})
+	})
+	metrics.observeRestore(time.Since(start), err)
+
+	entry := AuditEntry{
+		Action:  AuditActionRestoreCompleted,
+		Actor:   job.RequestedBy,
+		Params:  map[string]any{"backup": job.BackupID, "index": index, "job": job.ID},
+		Outcome: AuditSuccess,
+	}
+	defer func() { recordAudit(entry) }()
+
+	switch {
+	case err == nil:
+		jobs.finish(job.ID, JobSucceeded, nil)
+		publishEvent(EventRestoreFinished, m.config.Identifier, fmt.Sprintf("Backup %d restored", index), data)
+	case errors.Is(err, context.Canceled):
+		jobs.finish(job.ID, JobCanceled, nil)
+		entry.Outcome, entry.Error = AuditFailure, "canceled"
+		PluginLib.Log(fmt.Sprintf("%s Restore job %s was canceled, live save left unchanged", m.config.Identifier, job.ID), "Info")
+		publishEvent(EventRestoreFailed, m.config.Identifier, fmt.Sprintf("Restore of backup %d was canceled", index), data)
+	default:
+		jobs.finish(job.ID, JobFailed, err)
+		entry.Outcome, entry.Error = AuditFailure, err.Error()
+		PluginLib.Log(fmt.Sprintf("%s Restore job %s failed: %s", m.config.Identifier, job.ID, err.Error()), "Error")
+		publishEvent(EventRestoreFailed, m.config.Identifier, err.Error(), data)
+	}
+}
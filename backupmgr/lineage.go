@@ -0,0 +1,187 @@
+package backupmgr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SteamServerUI/PluginLib"
+)
+
+/*
+Lineage tracks which backup each backup descends from. The catalog keeps a head: the backup the
+live save currently descends from. Every new backup becomes a child of the head and the new head,
+and a restore moves the head to the restored backup, so the autosaves after a restore start a new
+branch. Backups stored before lineage was tracked descend from the previous backup by time.
+*/
+
+// LineageNode is a backup in the lineage tree
+type LineageNode struct {
+	// ID is the stable ID of the backup, see BackupGroup.ID
+	ID        string    `json:"id"`
+	Index     int       `json:"index"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Parent is the index of the backup this one descends from, nil for roots
+	Parent *int `json:"parent"`
+	// Live marks the backup the live save descends from, LiveBranch it and its ancestors
+	Live       bool `json:"live"`
+	LiveBranch bool `json:"liveBranch"`
+}
+
+// LineageTree is the lineage of all stored backups, oldest first
+type LineageTree struct {
+	Live  *int          `json:"live"`
+	Nodes []LineageNode `json:"nodes"`
+}
+
+// lineageKey returns the catalog key a stored file is tracked under. Legacy trios are tracked
+// through their world.bin, so the other parts of a trio return "".
+func (m *BackupManager) lineageKey(storedPath string) string {
+	name := trimStorageSuffixes(storedPath)
+	if !isSaveFile(name) && !strings.HasSuffix(name, ".bin") {
+		return ""
+	}
+	return m.catalogKey(BackupGroup{BinFile: storedPath})
+}
+
+// recordLineage makes a newly stored backup a child of the head and the new head.
+// The caller must hold m.mu.
+func (m *BackupManager) recordLineage(storedPath string) {
+	key := m.lineageKey(storedPath)
+	if key == "" {
+		return
+	}
+
+	err := m.updateCatalog(func(catalog *backupCatalog) bool {
+		// The game reuses trio names, so an earlier backup under this key is replaced
+		catalog.remove(key)
+
+		parent := catalog.Head
+		if parent == "" {
+			parent = m.previousBackupKey(key)
+		}
+		catalog.Backups[key] = CatalogEntry{Parent: parent}
+		catalog.Head = key
+		return true
+	})
+	if err != nil {
+		PluginLib.Log(fmt.Sprintf("%s Failed to record the lineage of %s: %s", m.config.Identifier, storedPath, err.Error()), "Error")
+	}
+}
+
+// previousBackupKey returns the catalog key of the newest backup other than key, or "" if there is none.
+// The caller must hold m.mu.
+func (m *BackupManager) previousBackupKey(key string) string {
+	groups, err := m.getBackupGroups()
+	if err != nil {
+		return ""
+	}
+	var previous BackupGroup
+	for _, group := range groups {
+		if m.catalogKey(group) != key && group.ModTime.After(previous.ModTime) {
+			previous = group
+		}
+	}
+	if previous.BinFile == "" {
+		return ""
+	}
+	return m.catalogKey(previous)
+}
+
+// markRestored moves the head to a restored backup so the next backups branch off it.
+// The caller must hold m.mu.
+func (m *BackupManager) markRestored(group BackupGroup) {
+	key := m.catalogKey(group)
+	err := m.updateCatalog(func(catalog *backupCatalog) bool {
+		if _, found := catalog.Backups[key]; !found {
+			catalog.Backups[key] = CatalogEntry{Parent: m.previousBackupKeyBefore(group)}
+		}
+		catalog.Head = key
+		return true
+	})
+	if err != nil {
+		PluginLib.Log(fmt.Sprintf("%s Failed to record the restore of backup %d in the lineage: %s", m.config.Identifier, group.Index, err.Error()), "Error")
+	}
+}
+
+// previousBackupKeyBefore returns the catalog key of the newest backup older than group, or "".
+// The caller must hold m.mu.
+func (m *BackupManager) previousBackupKeyBefore(group BackupGroup) string {
+	groups, err := m.getBackupGroups()
+	if err != nil {
+		return ""
+	}
+	var previous BackupGroup
+	for _, candidate := range groups {
+		if candidate.ModTime.Before(group.ModTime) && candidate.ModTime.After(previous.ModTime) {
+			previous = candidate
+		}
+	}
+	if previous.BinFile == "" {
+		return ""
+	}
+	return m.catalogKey(previous)
+}
+
+// BackupTree returns the lineage of all stored backups
+func (m *BackupManager) BackupTree() (LineageTree, error) {
+	m.mu.Lock()
+	groups, err := m.getBackupGroups()
+	if err != nil {
+		m.mu.Unlock()
+		return LineageTree{}, err
+	}
+	catalog, err := m.loadCatalog()
+	m.mu.Unlock()
+	if err != nil {
+		return LineageTree{}, err
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].ModTime.Before(groups[j].ModTime)
+	})
+	byKey := make(map[string]int, len(groups))
+	for i, group := range groups {
+		byKey[m.catalogKey(group)] = i
+	}
+
+	tree := LineageTree{Nodes: make([]LineageNode, len(groups))}
+	parents := make([]int, len(groups)) // position of each node's parent, -1 for roots
+	for i, group := range groups {
+		node := LineageNode{ID: group.ID, Index: group.Index, Type: backupTypeTrio, CreatedAt: group.ModTime}
+		if isSaveFile(group.BinFile) {
+			node.Type = backupTypeSave
+		}
+
+		parents[i] = -1
+		entry, tracked := catalog.Backups[m.catalogKey(group)]
+		if position, ok := byKey[entry.Parent]; ok && entry.Parent != "" && position != i {
+			parents[i] = position
+		} else if i > 0 && (!tracked || entry.ImportedFrom == "") {
+			// Untracked backups descend from the previous one, imported ones are roots
+			parents[i] = i - 1
+		}
+		if parents[i] >= 0 {
+			parentIndex := groups[parents[i]].Index
+			node.Parent = &parentIndex
+		}
+		tree.Nodes[i] = node
+	}
+
+	// Without a recorded head the live save descends from the newest backup
+	live, ok := byKey[catalog.Head]
+	if !ok {
+		live = len(groups) - 1
+	}
+	if live >= 0 {
+		tree.Live = &tree.Nodes[live].Index
+		tree.Nodes[live].Live = true
+		// The visited check guards against cycles left by a hand-edited catalog
+		for position := live; position >= 0 && !tree.Nodes[position].LiveBranch; position = parents[position] {
+			tree.Nodes[position].LiveBranch = true
+		}
+	}
+	return tree, nil
+}
@@ -0,0 +1,289 @@
+package backupmgr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/SteamServerUI/PluginLib"
+)
+
+const (
+	lockFileName          = ".backupmanager.lock"
+	lockHeartbeatInterval = 30 * time.Second
+	lockStaleAfter        = 2 * time.Minute
+	lockRetryInterval     = 5 * time.Second
+	lockAttempts          = 3
+)
+
+// errLockNotHeld is returned by operations that need the SafeBackupDir lock while it isn't held
+var errLockNotHeld = errors.New("this backup manager does not hold the safe backup directory lock")
+
+// lockInfo is the content of the lock file in SafeBackupDir
+type lockInfo struct {
+	PID        int       `json:"pid"`
+	Host       string    `json:"host"`
+	Identifier string    `json:"identifier"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	Heartbeat  time.Time `json:"heartbeat"`
+}
+
+// stale reports whether the owner stopped refreshing the lock
+func (l lockInfo) stale() bool {
+	return time.Since(l.Heartbeat) > lockStaleAfter
+}
+
+// lockPath returns the path of the lock file
+func (m *BackupManager) lockPath() string {
+	return filepath.Join(m.config.SafeBackupDir, lockFileName)
+}
+
+// acquireLock blocks until this manager owns the SafeBackupDir lock or its context is canceled,
+// and keeps it until Shutdown. Locks whose owner stopped sending heartbeats or whose process
+// is gone are taken over.
+func (m *BackupManager) acquireLock(identifier string) error {
+	for {
+		acquired, owner, err := m.retainLock(identifier)
+		if err != nil {
+			return fmt.Errorf("%s failed to acquire the safe backup dir lock: %w", identifier, err)
+		}
+		if acquired {
+			m.lockMu.Lock()
+			m.lockStarted = true
+			m.lockMu.Unlock()
+			PluginLib.Log(fmt.Sprintf("%s Acquired safe backup dir lock", identifier), "Debug")
+			return nil
+		}
+
+		PluginLib.Log(fmt.Sprintf("%s Safe backup dir is locked by %s (pid %d on %s), waiting...", identifier, owner.Identifier, owner.PID, owner.Host), "Info")
+		select {
+		case <-m.ctx.Done():
+			return fmt.Errorf("%s gave up waiting for the safe backup dir lock: %w", identifier, m.ctx.Err())
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// borrowLock takes the SafeBackupDir lock for a single operation, like a restore requested while
+// the manager still waits for the save folder. If the manager already holds the lock it is shared.
+// The returned function gives the lock back.
+func (m *BackupManager) borrowLock() (func(), error) {
+	acquired, owner, err := m.retainLock(m.config.Identifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire the safe backup dir lock: %w", err)
+	}
+	if !acquired {
+		return nil, fmt.Errorf("%w, it is held by %s (pid %d on %s)", errLockNotHeld, owner.Identifier, owner.PID, owner.Host)
+	}
+	return m.releaseLock, nil
+}
+
+// retainLock adds a reference to the lock, taking it if this manager doesn't hold it yet.
+// If another instance holds it, it reports false and the current owner.
+func (m *BackupManager) retainLock(identifier string) (bool, lockInfo, error) {
+	m.lockMu.Lock()
+	defer m.lockMu.Unlock()
+
+	if m.lockRefs > 0 && m.lockHeld.Load() {
+		m.lockRefs++
+		return true, lockInfo{}, nil
+	}
+
+	info, owner, err := m.tryLock(identifier)
+	if err != nil || info == nil {
+		return false, owner, err
+	}
+	if m.lockRefs > 0 {
+		// The previous hold was lost to another instance, its heartbeat already stopped
+		close(m.lockStop)
+	}
+	m.lockRefs++
+	m.lockHeld.Store(true)
+	m.lockStop = make(chan struct{})
+	go m.heartbeatLock(identifier, *info, m.lockStop)
+	return true, lockInfo{}, nil
+}
+
+// tryLock makes a single attempt at creating the lock file. Stale locks are taken over.
+// It returns the written lock, or the current owner if another instance holds the lock.
+func (m *BackupManager) tryLock(identifier string) (*lockInfo, lockInfo, error) {
+	host, _ := os.Hostname()
+	path := m.lockPath()
+
+	// A few rounds cover races with instances releasing or taking over the lock at the same time
+	for attempt := 0; attempt < lockAttempts; attempt++ {
+		now := time.Now()
+		info := lockInfo{PID: os.Getpid(), Host: host, Identifier: m.config.Identifier, AcquiredAt: now, Heartbeat: now}
+		err := createLock(path, info)
+		if err == nil {
+			return &info, lockInfo{}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, lockInfo{}, err
+		}
+
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, lockInfo{}, err
+		}
+		current, err := parseLock(data)
+		switch {
+		case err != nil:
+			// A lock that was just created may not be written yet
+			if stat, statErr := os.Stat(path); statErr != nil || time.Since(stat.ModTime()) <= lockStaleAfter {
+				return nil, lockInfo{Identifier: "an unknown instance"}, nil
+			}
+			PluginLib.Log(fmt.Sprintf("%s Unreadable lock file, taking it over: %s", identifier, err.Error()), "Error")
+		case current.stale():
+			PluginLib.Log(fmt.Sprintf("%s Taking over stale lock of %s (pid %d on %s, last heartbeat %s)", identifier, current.Identifier, current.PID, current.Host, current.Heartbeat.Format(time.RFC3339)), "Info")
+		case current.Host == host && current.PID != os.Getpid() && !processAlive(current.PID):
+			PluginLib.Log(fmt.Sprintf("%s Taking over lock of %s, its process %d is no longer running", identifier, current.Identifier, current.PID), "Info")
+		default:
+			return nil, current, nil
+		}
+
+		// Whoever takes over the lock still has to win the exclusive create in the next round
+		takeOverLock(path, data)
+	}
+	return nil, lockInfo{Identifier: "another instance"}, nil
+}
+
+// takeOverLock removes a stale lock file. The file is renamed aside first and compared with what
+// was read, so a lock that was refreshed or replaced in the meantime is put back instead of removed.
+func takeOverLock(path string, stale []byte) {
+	aside := fmt.Sprintf("%s.%d-%d", path, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(path, aside); err != nil {
+		// Someone else moved it first
+		return
+	}
+	defer os.Remove(aside)
+
+	if data, err := os.ReadFile(aside); err == nil && bytes.Equal(data, stale) {
+		return
+	}
+	// Linking fails if a new lock was created meanwhile, which then wins
+	if err := os.Link(aside, path); err != nil && !errors.Is(err, os.ErrExist) {
+		PluginLib.Log(fmt.Sprintf("Failed to put back lock file %s: %s", path, err.Error()), "Error")
+	}
+}
+
+// heartbeatLock refreshes the lock until stop is closed or the lock was lost
+func (m *BackupManager) heartbeatLock(identifier string, info lockInfo, stop <-chan struct{}) {
+	ticker := time.NewTicker(lockHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			owner, err := readLock(m.lockPath())
+			if err != nil || owner.Identifier != m.config.Identifier {
+				m.lockHeld.Store(false)
+				m.recordError(fmt.Errorf("lost the safe backup dir lock to another instance"))
+				return
+			}
+			info.Heartbeat = time.Now()
+			if err := writeLock(m.lockPath(), info); err != nil {
+				PluginLib.Log(fmt.Sprintf("%s Failed to refresh lock file: %s", identifier, err.Error()), "Error")
+			}
+		}
+	}
+}
+
+// releaseLock drops a reference to the lock and removes the lock file once the last one is gone
+func (m *BackupManager) releaseLock() {
+	m.lockMu.Lock()
+	defer m.lockMu.Unlock()
+
+	if m.lockRefs == 0 {
+		return
+	}
+	m.lockRefs--
+	if m.lockRefs > 0 {
+		return
+	}
+	close(m.lockStop)
+	if !m.lockHeld.Swap(false) {
+		return
+	}
+	if owner, err := readLock(m.lockPath()); err == nil && owner.Identifier == m.config.Identifier {
+		os.Remove(m.lockPath())
+	}
+}
+
+// releaseStartedLock gives back the lock taken by acquireLock, if it was taken
+func (m *BackupManager) releaseStartedLock() {
+	m.lockMu.Lock()
+	started := m.lockStarted
+	m.lockStarted = false
+	m.lockMu.Unlock()
+
+	if started {
+		m.releaseLock()
+	}
+}
+
+// holdsLock reports whether this manager currently owns the SafeBackupDir lock
+func (m *BackupManager) holdsLock() bool {
+	return m.lockHeld.Load()
+}
+
+// readLock reads a lock file
+func readLock(path string) (lockInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return lockInfo{}, err
+	}
+	return parseLock(data)
+}
+
+// parseLock decodes the content of a lock file
+func parseLock(data []byte) (lockInfo, error) {
+	var info lockInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, fmt.Errorf("invalid lock file: %w", err)
+	}
+	return info, nil
+}
+
+// createLock creates the lock file, failing with os.ErrExist if it already exists
+func createLock(path string, info lockInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// writeLock atomically replaces the lock file
+func writeLock(path string, info lockInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,13 @@
+//go:build linux
+// +build linux
+
+package backupmgr
+
+import "syscall"
+
+// processAlive reports whether a process with the given PID is running on this host
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	// EPERM means the process exists but belongs to another user
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,125 @@
+package backupmgr
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// exitedPID returns the PID of a process that already exited
+func exitedPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestLockStale(t *testing.T) {
+	tests := []struct {
+		name      string
+		heartbeat time.Duration
+		stale     bool
+	}{
+		{"just refreshed", 0, false},
+		{"missed a heartbeat", -2 * lockHeartbeatInterval, false},
+		{"past the stale limit", -lockStaleAfter - time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := lockInfo{Heartbeat: time.Now().Add(tt.heartbeat)}
+			if got := info.stale(); got != tt.stale {
+				t.Errorf("stale() = %v, want %v", got, tt.stale)
+			}
+		})
+	}
+}
+
+func TestTryLockTakeover(t *testing.T) {
+	host, _ := os.Hostname()
+	now := time.Now()
+	tests := []struct {
+		name string
+		// setup writes the lock file found by the new instance, nothing for no lock
+		setup    func(t *testing.T, path string)
+		acquired bool
+	}{
+		{"no lock", func(t *testing.T, path string) {}, true},
+		{"held by another host", func(t *testing.T, path string) {
+			writeLock(path, lockInfo{PID: 1, Host: "other-host", Identifier: "other", Heartbeat: now})
+		}, false},
+		{"held by a running process", func(t *testing.T, path string) {
+			writeLock(path, lockInfo{PID: os.Getppid(), Host: host, Identifier: "other", Heartbeat: now})
+		}, false},
+		{"stale heartbeat", func(t *testing.T, path string) {
+			writeLock(path, lockInfo{PID: 1, Host: "other-host", Identifier: "other", Heartbeat: now.Add(-lockStaleAfter - time.Minute)})
+		}, true},
+		{"owner process exited", func(t *testing.T, path string) {
+			writeLock(path, lockInfo{PID: exitedPID(t), Host: host, Identifier: "other", Heartbeat: now})
+		}, true},
+		{"unreadable and fresh", func(t *testing.T, path string) {
+			os.WriteFile(path, []byte("{"), 0o644)
+		}, false},
+		{"unreadable and old", func(t *testing.T, path string) {
+			os.WriteFile(path, []byte("{"), 0o644)
+			old := now.Add(-lockStaleAfter - time.Minute)
+			os.Chtimes(path, old, old)
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t.TempDir(), "Mars")
+			if err := os.MkdirAll(cfg.SafeBackupDir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			m := NewBackupManager(cfg)
+			t.Cleanup(m.Shutdown)
+			tt.setup(t, m.lockPath())
+
+			info, _, err := m.tryLock(cfg.Identifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (info != nil) != tt.acquired {
+				t.Fatalf("acquired = %v, want %v", info != nil, tt.acquired)
+			}
+			owner, err := readLock(m.lockPath())
+			if tt.acquired && (err != nil || owner.Identifier != cfg.Identifier || owner.PID != os.Getpid()) {
+				t.Errorf("lock file = %+v, %v, want it owned by this instance", owner, err)
+			}
+			if !tt.acquired && owner.Identifier == cfg.Identifier {
+				t.Error("lock file was taken over")
+			}
+		})
+	}
+}
+
+func TestBorrowLockSharesReferences(t *testing.T) {
+	cfg := testConfig(t.TempDir(), "Mars")
+	m := newTestManager(t, cfg)
+	path := filepath.Join(cfg.SafeBackupDir, lockFileName)
+
+	release, err := m.borrowLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if _, err := os.Stat(path); err != nil || !m.holdsLock() {
+		t.Fatal("returning a borrowed lock released the lock held since start")
+	}
+
+	other := NewBackupManager(testConfig(filepath.Dir(filepath.Dir(cfg.SafeBackupDir)), "Mars"))
+	other.config.Identifier = "other"
+	t.Cleanup(other.Shutdown)
+	if _, err := other.borrowLock(); err == nil {
+		t.Fatal("another instance borrowed a lock that is held")
+	}
+
+	m.releaseStartedLock()
+	if _, err := os.Stat(path); !os.IsNotExist(err) || m.holdsLock() {
+		t.Fatal("the lock file was left behind after the last reference was released")
+	}
+}
@@ -0,0 +1,27 @@
+//go:build windows
+// +build windows
+
+package backupmgr
+
+import "syscall"
+
+const (
+	processQueryLimitedInformation = 0x1000
+	processStillActive             = 259
+)
+
+// processAlive reports whether a process with the given PID is running on this host
+func processAlive(pid int) bool {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		// Access denied means the process exists but belongs to another user
+		return err == syscall.ERROR_ACCESS_DENIED
+	}
+	defer syscall.CloseHandle(handle)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(handle, &code); err != nil {
+		return true
+	}
+	return code == processStillActive
+}
@@ -0,0 +1,41 @@
+package backupmgr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SteamServerUI/PluginLib"
+	"github.com/SteamServerUI/StationeersBackupManager/global"
+)
+
+// TestMain sets up logging and keeps the audit log out of the source tree
+func TestMain(m *testing.M) {
+	PluginLib.InitConfig(global.PluginName, global.DefaultLogLevel)
+
+	dir, err := os.MkdirTemp("", "backupmgr-test")
+	if err != nil {
+		panic(err)
+	}
+	auditLog = &auditLogger{path: filepath.Join(dir, "audit.jsonl")}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newTestManager returns a manager for cfg that holds the lock on its safe backup dir but watches nothing
+func newTestManager(t *testing.T, cfg BackupConfig) *BackupManager {
+	t.Helper()
+	for _, path := range []string{cfg.BackupDir, cfg.SafeBackupDir} {
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := NewBackupManager(cfg)
+	if err := m.acquireLock(cfg.Identifier); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Shutdown)
+	return m
+}
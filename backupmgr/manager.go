@@ -2,6 +2,7 @@ package backupmgr
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -25,6 +26,7 @@ func (m *BackupManager) Initialize(identifier string) <-chan error {
 	defer m.mu.Unlock()
 
 	result := make(chan error, 1)
+	m.setState(stateWaitingForSaveDir)
 
 	go func() {
 		defer close(result)
@@ -86,23 +88,52 @@ func (m *BackupManager) Start(identifier string) error {
 	PluginLib.Log(fmt.Sprintf("%s is waiting for save folder initialization...", identifier), "Debug")
 	initResult := <-m.Initialize(identifier)
 	if initResult != nil {
-		return fmt.Errorf("%s failed to initialize backup manager : %w", identifier, initResult)
+		err := fmt.Errorf("%s failed to initialize backup manager : %w", identifier, initResult)
+		m.recordError(err)
+		return err
 	}
 	PluginLib.Log(fmt.Sprintf("%s Backup manager instance started", identifier), "Info")
 
-	// Start file watcher
-	watcher, err := newFsWatcher(m.config.BackupDir, identifier)
+	// Make sure no other instance writes to the same safe backup dir
+	if err := m.acquireLock(identifier); err != nil {
+		m.recordError(err)
+		return err
+	}
+	m.mu.Lock()
+	m.refreshBackupSetStats()
+	m.mu.Unlock()
+
+	// Start the file watcher before reconciling so no autosave falls between the two
+	watcher, err := newBackupWatcher(m.config, identifier)
 	if err != nil {
-		return fmt.Errorf("failed to create autosave watcher: %w", err)
+		err = fmt.Errorf("failed to create autosave watcher: %w", err)
+		m.recordError(err)
+		return err
 	}
+	m.mu.Lock()
 	m.watcher = watcher
-	go m.watchBackups(identifier)
+	m.mu.Unlock()
+	m.setState(stateWatching)
+	go m.watchBackups(watcher, identifier)
+
+	// Catch up on autosaves written while nothing was watching
+	copied, err := m.reconcile(identifier)
+	if err != nil {
+		PluginLib.Log(fmt.Sprintf("%s Startup reconciliation failed: %s", identifier, err.Error()), "Error")
+	} else if copied > 0 {
+		PluginLib.Log(fmt.Sprintf("%s Startup reconciliation copied %d missed autosave files", identifier, copied), "Info")
+	}
+
+	if m.config.scheduleEnabled() {
+		m.wg.Add(1)
+		go m.runScheduler(identifier)
+	}
 
 	return nil
 }
 
 // watchBackups monitors the backup directory for new files
-func (m *BackupManager) watchBackups(identifier string) {
+func (m *BackupManager) watchBackups(watcher backupWatcher, identifier string) {
 	m.wg.Add(1)
 	defer m.wg.Done()
 
@@ -114,60 +145,142 @@ func (m *BackupManager) watchBackups(identifier string) {
 		case <-m.ctx.Done():
 			PluginLib.Log(fmt.Sprintf("%s WatchBackups stopped due to context cancellation", identifier), "Info")
 			return
-		case event, ok := <-m.watcher.events:
+		case event, ok := <-watcher.Events():
 			if !ok {
 				return
 			}
-			if event.Op&fsnotify.Create == fsnotify.Create {
-				PluginLib.Log(fmt.Sprintf("%s New backup file detected: %s", identifier, event.Name), "Info")
+			// Saves written as temp-then-rename or rewritten in place show up as Rename or Write
+			if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) != 0 {
 				m.handleNewBackup(event.Name)
 			}
-		case err, ok := <-m.watcher.errors:
+		case err, ok := <-watcher.Errors():
 			if !ok {
 				return
 			}
 			PluginLib.Log(fmt.Sprintf("%s Backup watcher error: %s", identifier, err.Error()), "Error")
+			metrics.incWatcherErrors()
+			m.recordError(fmt.Errorf("watcher error: %w", err))
+			publishEvent(EventWatcherError, identifier, err.Error(), nil)
 		}
 	}
 }
 
-// handleNewBackup processes a newly created backup file
+// handleNewBackup processes a newly created or modified backup file. Repeated events for a file
+// that is already waiting to be copied are ignored, the pending copy picks up the latest contents.
 func (m *BackupManager) handleNewBackup(filePath string) {
 	if !isValidBackupFile(filepath.Base(filePath)) {
 		return
 	}
+	// Rename events carry the old name; only act if a file still exists at that path
+	if info, err := os.Stat(filePath); err != nil || info.IsDir() {
+		return
+	}
+	if !m.markPending(filePath) {
+		return
+	}
+
+	PluginLib.Log(fmt.Sprintf("%s New backup file detected: %s", m.config.Identifier, filePath), "Info")
+	publishEvent(EventBackupDetected, m.config.Identifier, "New backup file detected", map[string]any{"file": filePath})
 
 	m.wg.Add(1)
 	go func() {
 		defer m.wg.Done()
+		defer m.clearPending(filePath)
 
-		time.Sleep(m.config.WaitTime)
+		// Reloads and shutdowns wait for the copy, the next manager may watch a different BackupDir
+		// and would never reconcile it. Only an expired shutdown deadline skips it.
+		select {
+		case <-time.After(m.config.WaitTime):
+		case <-m.abandon:
+			m.logAbandonedCopy(filePath)
+			return
+		}
+		waitForStableFile(filePath, m.config.StabilityWindow)
+		select {
+		case <-m.abandon:
+			m.logAbandonedCopy(filePath)
+			return
+		default:
+		}
 
 		m.mu.Lock()
 		defer m.mu.Unlock()
 
 		fileName := filepath.Base(filePath)
+		copyStart := time.Now()
+		publishEvent(EventCopyStarted, m.config.Identifier, "Copying backup to safe location", map[string]any{"file": fileName})
+
 		relativePath, err := filepath.Rel(m.config.BackupDir, filePath)
 		if err != nil {
 			PluginLib.Log(fmt.Sprintf("Error getting relative path for %s: %s", filePath, err.Error()), "Error")
+			m.recordCopyFailure(fileName, err)
 			return
 		}
 		dstPath := filepath.Join(m.config.SafeBackupDir, relativePath)
 
 		if err := os.MkdirAll(filepath.Dir(dstPath), os.ModePerm); err != nil {
 			PluginLib.Log(fmt.Sprintf("Error creating destination dir for %s: %s", dstPath, err.Error()), "Error")
+			m.recordCopyFailure(fileName, err)
 			return
 		}
 
-		if err := copyFile(filePath, dstPath); err != nil {
+		dstPath, err = m.storeBackupFile(filePath, dstPath)
+		if errors.Is(err, errManagerRetired) {
+			PluginLib.Log(fmt.Sprintf("%s Leaving %s to the next backup manager: %s", m.config.Identifier, fileName, err.Error()), "Info")
+			return
+		}
+		if err != nil {
 			PluginLib.Log(fmt.Sprintf("Error copying backup %s: %s", fileName, err.Error()), "Error")
+			m.recordCopyFailure(fileName, err)
 			return
 		}
 
+		metrics.observeCopy(time.Since(copyStart))
+		m.recordBackup()
+		m.recordLineage(dstPath)
 		PluginLib.Log(fmt.Sprintf("Backup successfully copied to safe location: %s", dstPath), "Info")
+		publishEvent(EventCopyFinished, m.config.Identifier, "Backup copied to safe location", map[string]any{"file": fileName, "destination": dstPath})
+
+		m.replicate(dstPath)
+		m.applyRetention()
+		m.refreshBackupSetStats()
 	}()
 }
 
+// logAbandonedCopy logs a pending copy that was skipped because the shutdown deadline passed
+func (m *BackupManager) logAbandonedCopy(filePath string) {
+	PluginLib.Log(fmt.Sprintf("%s Skipping pending copy of %s, the shutdown deadline passed", m.config.Identifier, filePath), "Error")
+}
+
+// markPending records a file as waiting to be copied and reports false if it already was
+func (m *BackupManager) markPending(filePath string) bool {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+
+	if m.pending == nil {
+		m.pending = make(map[string]struct{})
+	}
+	if _, ok := m.pending[filePath]; ok {
+		return false
+	}
+	m.pending[filePath] = struct{}{}
+	return true
+}
+
+// clearPending removes a file from the pending set once its copy finished
+func (m *BackupManager) clearPending(filePath string) {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+	delete(m.pending, filePath)
+}
+
+// recordCopyFailure tracks a failed copy in metrics, status and the event stream
+func (m *BackupManager) recordCopyFailure(fileName string, err error) {
+	metrics.incCopyFailures()
+	m.recordError(fmt.Errorf("failed to copy %s: %w", fileName, err))
+	publishEvent(EventCopyFailed, m.config.Identifier, err.Error(), map[string]any{"file": fileName})
+}
+
 // ListBackups returns information about available backups
 // limit: number of recent backups to return (0 for all)
 func (m *BackupManager) ListBackups(limit int) ([]BackupGroup, error) {
@@ -191,6 +304,75 @@ func (m *BackupManager) ListBackups(limit int) ([]BackupGroup, error) {
 	return groups, nil
 }
 
+// ErrBackupNotFound is returned when no backup with the requested index exists
+var ErrBackupNotFound = errors.New("no backup found")
+
+// GetBackupGroup returns the backup group with the given index
+func (m *BackupManager) GetBackupGroup(index int) (BackupGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	groups, err := m.getBackupGroups()
+	if err != nil {
+		return BackupGroup{}, err
+	}
+	for _, group := range groups {
+		if group.Index == index {
+			return group, nil
+		}
+	}
+	return BackupGroup{}, fmt.Errorf("%w with index %d", ErrBackupNotFound, index)
+}
+
+// GetBackup returns the backup group with the given ID, see BackupGroup.ID
+func (m *BackupManager) GetBackup(id string) (BackupGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.findBackup(id)
+}
+
+// findBackup returns the backup group with the given ID. The caller must hold m.mu.
+func (m *BackupManager) findBackup(id string) (BackupGroup, error) {
+	groups, err := m.getBackupGroups()
+	if err != nil {
+		return BackupGroup{}, err
+	}
+	for _, group := range groups {
+		if group.ID == id {
+			return group, nil
+		}
+	}
+	return BackupGroup{}, fmt.Errorf("%w with id %q", ErrBackupNotFound, id)
+}
+
+// DeleteBackup removes all files of the backup with the given ID from the safe backup location
+func (m *BackupManager) DeleteBackup(id string) error {
+	release, err := m.borrowLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	groups, err := m.getBackupGroups()
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if group.ID == id {
+			if err := m.deleteBackupGroup(group); err != nil {
+				return fmt.Errorf("failed to delete backup %s: %w", id, err)
+			}
+			m.refreshBackupSetStats()
+			PluginLib.Log(fmt.Sprintf("%s Deleted backup %s", m.config.Identifier, id), "Info")
+			return nil
+		}
+	}
+	return fmt.Errorf("%w with id %q", ErrBackupNotFound, id)
+}
+
 // Shutdown stops all backup operations
 func (m *BackupManager) Shutdown() {
 	PluginLib.Log("Shutting down previous backup manager...", "Info")
@@ -208,14 +390,49 @@ func (m *BackupManager) Shutdown() {
 		PluginLib.Log("File watcher closed", "Info")
 	}
 	m.mu.Unlock()
+	m.setState(stateStopped)
 
 	// Wait for all goroutines to finish
 	PluginLib.Log("Waiting for background tasks to complete...", "Info")
 	m.wg.Wait()
+	m.releaseStartedLock()
 
 	PluginLib.Log("Backup manager shut down completely", "Info")
 }
 
+// ErrShutdownTimeout is returned when background tasks did not finish within the shutdown deadline
+var ErrShutdownTimeout = errors.New("timed out waiting for background tasks to finish")
+
+// shutdownRollbackGrace is how long canceled restores get to roll back after the shutdown deadline passed
+const shutdownRollbackGrace = 5 * time.Second
+
+// ShutdownWithin stops all backup operations like Shutdown, but gives up waiting after timeout.
+// Pending copies and running restores may finish until the deadline passes. Copies still waiting
+// for their file then are skipped, running restores are canceled and get a short grace period to roll back.
+func (m *BackupManager) ShutdownWithin(timeout time.Duration) error {
+	done := make(chan struct{})
+	go func() {
+		m.Shutdown()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+	}
+
+	PluginLib.Log("Shutdown deadline reached, skipping pending copies and canceling running restores", "Info")
+	m.abandonOnce.Do(func() { close(m.abandon) })
+	cancelAllJobs()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(shutdownRollbackGrace):
+		return ErrShutdownTimeout
+	}
+}
+
 // NewBackupManager creates a new BackupManager instance
 func NewBackupManager(cfg BackupConfig) *BackupManager {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -224,9 +441,19 @@ func NewBackupManager(cfg BackupConfig) *BackupManager {
 		cfg.WaitTime = defaultWaitTime
 	}
 
-	return &BackupManager{
-		config: cfg,
-		ctx:    ctx,
-		cancel: cancel,
+	m := &BackupManager{
+		config:     cfg,
+		ctx:        ctx,
+		cancel:     cancel,
+		jobQueue:   make(chan *trackedJob, jobQueueSize),
+		abandon:    make(chan struct{}),
+		state:      stateInitializing,
+		stateSince: time.Now(),
 	}
+
+	// The job worker runs for the lifetime of the instance, restores may be queued before Start finishes
+	m.wg.Add(1)
+	go m.runJobs()
+
+	return m
 }
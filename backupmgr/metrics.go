@@ -0,0 +1,209 @@
+package backupmgr
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the histogram bucket upper bounds in seconds used for copy and restore durations
+var durationBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// histogram is a minimal cumulative Prometheus histogram
+type histogram struct {
+	counts []uint64 // per bucket, non-cumulative
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(seconds float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(durationBuckets))
+	}
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// write renders the histogram series; labels is an optional comma-free label list like `result="success"`
+func (h *histogram) write(w io.Writer, name, labels string) {
+	bucketLabels, seriesLabels := "", ""
+	if labels != "" {
+		bucketLabels = labels + ","
+		seriesLabels = "{" + labels + "}"
+	}
+
+	var cumulative uint64
+	for i, bound := range durationBuckets {
+		if h.counts != nil {
+			cumulative += h.counts[i]
+		}
+		fmt.Fprintf(w, "%s_bucket{%sle=\"%g\"} %d\n", name, bucketLabels, bound, cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, bucketLabels, h.count)
+	fmt.Fprintf(w, "%s_sum%s %g\n", name, seriesLabels, h.sum)
+	fmt.Fprintf(w, "%s_count%s %d\n", name, seriesLabels, h.count)
+}
+
+// managerMetrics holds process-wide counters; they survive manager reloads
+type managerMetrics struct {
+	mu              sync.Mutex
+	copyDurations   histogram
+	copyFailures    uint64
+	restoreDuration map[string]*histogram // by result
+	watcherErrors   uint64
+}
+
+var metrics = &managerMetrics{restoreDuration: make(map[string]*histogram)}
+
+func (mm *managerMetrics) observeCopy(d time.Duration) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.copyDurations.observe(d.Seconds())
+}
+
+func (mm *managerMetrics) incCopyFailures() {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.copyFailures++
+}
+
+func (mm *managerMetrics) observeRestore(d time.Duration, err error) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	h, ok := mm.restoreDuration[result]
+	if !ok {
+		h = &histogram{}
+		mm.restoreDuration[result] = h
+	}
+	h.observe(d.Seconds())
+}
+
+func (mm *managerMetrics) incWatcherErrors() {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.watcherErrors++
+}
+
+// backupSetStats summarizes the backups currently stored in SafeBackupDir
+type backupSetStats struct {
+	count      int
+	totalBytes int64
+	newest     time.Time
+}
+
+// refreshBackupSetStats updates the stats served to metric scrapes after the stored backups changed,
+// so scrapes never wait for m.mu. If the backups can't be listed the stats are dropped.
+// The caller must hold m.mu.
+func (m *BackupManager) refreshBackupSetStats() {
+	stats, err := m.backupSetStatsLocked()
+	if err != nil {
+		m.setStats.Store(nil)
+		return
+	}
+	m.setStats.Store(&stats)
+}
+
+// backupSetStatsLocked gathers count, size and newest backup time of the stored backups.
+// The caller must hold m.mu.
+func (m *BackupManager) backupSetStatsLocked() (backupSetStats, error) {
+	var stats backupSetStats
+	groups, err := m.getBackupGroups()
+	if err != nil {
+		return stats, err
+	}
+	stats.count = len(groups)
+	for _, group := range groups {
+		if group.ModTime.After(stats.newest) {
+			stats.newest = group.ModTime
+		}
+		stats.totalBytes += groupSize(group)
+	}
+	return stats, nil
+}
+
+// allManagerStates lists every state so the state gauge always exposes a complete set of series
+var allManagerStates = []managerState{stateInitializing, stateWaitingForSaveDir, stateWatching, stateDegraded, stateStopped}
+
+// WriteMetrics writes all metrics in the Prometheus text exposition format
+func WriteMetrics(w io.Writer, m *BackupManager) {
+	if m != nil {
+		world := escapeLabelValue(m.config.SaveName)
+		if stats := m.setStats.Load(); stats != nil {
+			fmt.Fprintln(w, "# HELP sbm_backups Number of backup groups stored in the safe backup directory.")
+			fmt.Fprintln(w, "# TYPE sbm_backups gauge")
+			fmt.Fprintf(w, "sbm_backups{world=\"%s\"} %d\n", world, stats.count)
+
+			fmt.Fprintln(w, "# HELP sbm_backups_bytes Total size of the stored backup files in bytes.")
+			fmt.Fprintln(w, "# TYPE sbm_backups_bytes gauge")
+			fmt.Fprintf(w, "sbm_backups_bytes{world=\"%s\"} %d\n", world, stats.totalBytes)
+
+			if !stats.newest.IsZero() {
+				fmt.Fprintln(w, "# HELP sbm_newest_backup_age_seconds Age of the newest stored backup in seconds.")
+				fmt.Fprintln(w, "# TYPE sbm_newest_backup_age_seconds gauge")
+				fmt.Fprintf(w, "sbm_newest_backup_age_seconds{world=\"%s\"} %g\n", world, time.Since(stats.newest).Seconds())
+			}
+		}
+
+		current := m.getState()
+		fmt.Fprintln(w, "# HELP sbm_manager_state Current backup manager state (1 for the active state).")
+		fmt.Fprintln(w, "# TYPE sbm_manager_state gauge")
+		for _, state := range allManagerStates {
+			value := 0
+			if state == current {
+				value = 1
+			}
+			fmt.Fprintf(w, "sbm_manager_state{world=\"%s\",state=\"%s\"} %d\n", world, state, value)
+		}
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP sbm_backup_copy_duration_seconds Time taken to copy a new autosave to the safe backup directory.")
+	fmt.Fprintln(w, "# TYPE sbm_backup_copy_duration_seconds histogram")
+	metrics.copyDurations.write(w, "sbm_backup_copy_duration_seconds", "")
+
+	fmt.Fprintln(w, "# HELP sbm_backup_copy_failures_total Number of failed autosave copies.")
+	fmt.Fprintln(w, "# TYPE sbm_backup_copy_failures_total counter")
+	fmt.Fprintf(w, "sbm_backup_copy_failures_total %d\n", metrics.copyFailures)
+
+	fmt.Fprintln(w, "# HELP sbm_restore_duration_seconds Time taken by restores, by result.")
+	fmt.Fprintln(w, "# TYPE sbm_restore_duration_seconds histogram")
+	results := make([]string, 0, len(metrics.restoreDuration))
+	for result := range metrics.restoreDuration {
+		results = append(results, result)
+	}
+	sort.Strings(results)
+	for _, result := range results {
+		metrics.restoreDuration[result].write(w, "sbm_restore_duration_seconds", fmt.Sprintf("result=\"%s\"", result))
+	}
+
+	fmt.Fprintln(w, "# HELP sbm_restores_total Number of restores, by result.")
+	fmt.Fprintln(w, "# TYPE sbm_restores_total counter")
+	for _, result := range results {
+		fmt.Fprintf(w, "sbm_restores_total{result=\"%s\"} %d\n", result, metrics.restoreDuration[result].count)
+	}
+
+	fmt.Fprintln(w, "# HELP sbm_watcher_errors_total Number of errors reported by the autosave watcher.")
+	fmt.Fprintln(w, "# TYPE sbm_watcher_errors_total counter")
+	fmt.Fprintf(w, "sbm_watcher_errors_total %d\n", metrics.watcherErrors)
+}
+
+// escapeLabelValue escapes a Prometheus label value
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
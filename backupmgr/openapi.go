@@ -0,0 +1,133 @@
+package backupmgr
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/SteamServerUI/StationeersBackupManager/global"
+)
+
+// openAPIVersion is the version of the API described by the OpenAPI document
+const openAPIVersion = "2.0.0"
+
+// pathParamPattern matches {name} segments in route paths, which use the same syntax in OpenAPI
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// OpenAPIDocument builds an OpenAPI 3 document from the route table
+func (h *HTTPHandler) OpenAPIDocument() map[string]any {
+	paths := make(map[string]any)
+	for _, route := range h.Routes() {
+		var pathParams []any
+		for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+			pathParams = append(pathParams, map[string]any{
+				"name": match[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+			})
+		}
+
+		item := make(map[string]any)
+		if route.Summary != "" {
+			item["summary"] = route.Summary
+		}
+		for _, op := range route.Operations {
+			item[strings.ToLower(op.Method)] = openAPIOperation(route, op, pathParams)
+		}
+		paths[route.Path] = item
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       global.PluginName + " API",
+			"version":     openAPIVersion,
+			"description": "Backups of Stationeers saves managed by the " + global.PluginName + " plugin for SteamServerUI.",
+		},
+		"servers": []any{map[string]any{"url": "/plugins/" + global.PluginName}},
+		"paths":   paths,
+		"components": map[string]any{
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "API token from the plugin settings. Only enforced once at least one token is configured.",
+				},
+			},
+			"schemas": map[string]any{
+				"Error": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"error": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"code":    map[string]any{"type": "string"},
+								"message": map[string]any{"type": "string"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// openAPIOperation describes a single operation of a route
+func openAPIOperation(route Route, op Operation, pathParams []any) map[string]any {
+	params := append([]any{}, pathParams...)
+	for _, param := range op.Query {
+		params = append(params, map[string]any{
+			"name": param.Name, "in": "query", "required": param.Required,
+			"description": param.Description, "schema": map[string]any{"type": "string"},
+		})
+	}
+	if op.QueryToken {
+		params = append(params, map[string]any{
+			"name": "access_token", "in": "query", "required": false,
+			"description": "API token, for clients that can't send the Authorization header", "schema": map[string]any{"type": "string"},
+		})
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]any{"description": http.StatusText(status)}
+	if op.Response != "" {
+		success["content"] = map[string]any{op.Response: map[string]any{}}
+	}
+	responses := map[string]any{strconv.Itoa(status): success}
+	if strings.HasPrefix(route.Path, "/api/v2/") {
+		responses["default"] = map[string]any{
+			"description": "Error",
+			"content": map[string]any{contentJSON: map[string]any{
+				"schema": map[string]any{"$ref": "#/components/schemas/Error"},
+			}},
+		}
+	}
+
+	responses["401"] = map[string]any{"description": "Missing or unknown API token, only when tokens are configured"}
+	responses["403"] = map[string]any{"description": "The API token lacks the " + op.Scope + " scope"}
+
+	operation := map[string]any{
+		"summary":   op.Summary,
+		"responses": responses,
+		"security":  []any{map[string]any{"bearerAuth": []any{}}},
+		"x-scope":   op.Scope,
+	}
+	if len(params) > 0 {
+		operation["parameters"] = params
+	}
+	if op.RequestBody != "" {
+		operation["requestBody"] = map[string]any{
+			"required":    true,
+			"description": op.RequestBody,
+			"content":     map[string]any{contentJSON: map[string]any{"schema": map[string]any{"type": "object"}}},
+		}
+	}
+	return operation
+}
+
+// OpenAPIHandler serves the OpenAPI document
+func (h *HTTPHandler) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.OpenAPIDocument())
+}
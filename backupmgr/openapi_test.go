@@ -0,0 +1,35 @@
+package backupmgr
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestOpenAPIDocumentCoversRoutes checks that every registered route and method is documented
+func TestOpenAPIDocumentCoversRoutes(t *testing.T) {
+	h := &HTTPHandler{}
+	paths, ok := h.OpenAPIDocument()["paths"].(map[string]any)
+	if !ok {
+		t.Fatal("OpenAPI document has no paths object")
+	}
+
+	for _, route := range h.Routes() {
+		item, ok := paths[route.Path].(map[string]any)
+		if !ok {
+			t.Errorf("route %s is missing from the OpenAPI document", route.Path)
+			continue
+		}
+		if len(route.Operations) == 0 && item["summary"] == nil {
+			t.Errorf("route %s has no operations and no summary in the OpenAPI document", route.Path)
+		}
+		for _, op := range route.Operations {
+			if _, ok := item[strings.ToLower(op.Method)]; !ok {
+				t.Errorf("%s %s is missing from the OpenAPI document", op.Method, route.Path)
+			}
+		}
+	}
+
+	if len(paths) != len(h.Routes()) {
+		t.Errorf("OpenAPI document has %d paths, the route table %d", len(paths), len(h.Routes()))
+	}
+}
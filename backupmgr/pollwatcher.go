@@ -0,0 +1,138 @@
+package backupmgr
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// defaultPollInterval is used when the polling watcher is enabled without an explicit interval
+const defaultPollInterval = 10 * time.Second
+
+// polledFile is the state of a file as seen by the last scan
+type polledFile struct {
+	size    int64
+	modTime time.Time
+}
+
+// pollWatcher detects changes by periodically scanning a directory tree. It is used where
+// fsnotify is unreliable, e.g. NFS/SMB mounts and some container bind mounts.
+type pollWatcher struct {
+	root     string
+	interval time.Duration
+	events   chan fsnotify.Event
+	errors   chan error
+	done     chan struct{}
+	files    map[string]polledFile
+}
+
+// newPollWatcher creates a polling watcher. Files that already exist don't produce events.
+func newPollWatcher(path string, interval time.Duration) (*pollWatcher, error) {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	w := &pollWatcher{
+		root:     filepath.Clean(path),
+		interval: interval,
+		events:   make(chan fsnotify.Event),
+		errors:   make(chan error),
+		done:     make(chan struct{}),
+	}
+
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	w.files = files
+
+	go w.run()
+	return w, nil
+}
+
+// Events returns the channel of synthetic create, write and remove events
+func (w *pollWatcher) Events() <-chan fsnotify.Event {
+	return w.events
+}
+
+// Errors returns the channel of scan errors
+func (w *pollWatcher) Errors() <-chan error {
+	return w.errors
+}
+
+// scan returns the current state of all files below the root
+func (w *pollWatcher) scan() (map[string]polledFile, error) {
+	files := make(map[string]polledFile)
+	err := filepath.WalkDir(w.root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			// The file vanished between listing and stat, it will show up as removed or new next time
+			return nil
+		}
+		files[path] = polledFile{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return files, err
+}
+
+// run scans on every tick and emits events for differences to the previous scan
+func (w *pollWatcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		files, err := w.scan()
+		if err != nil {
+			select {
+			case w.errors <- err:
+			case <-w.done:
+				return
+			}
+			continue
+		}
+
+		var changes []fsnotify.Event
+		for path, current := range files {
+			previous, existed := w.files[path]
+			switch {
+			case !existed:
+				changes = append(changes, fsnotify.Event{Name: path, Op: fsnotify.Create})
+			case current != previous:
+				changes = append(changes, fsnotify.Event{Name: path, Op: fsnotify.Write})
+			}
+		}
+		for path := range w.files {
+			if _, exists := files[path]; !exists {
+				changes = append(changes, fsnotify.Event{Name: path, Op: fsnotify.Remove})
+			}
+		}
+		w.files = files
+
+		for _, event := range changes {
+			select {
+			case w.events <- event:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+// close stops the polling loop
+func (w *pollWatcher) close() {
+	close(w.done)
+}
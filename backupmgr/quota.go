@@ -0,0 +1,166 @@
+package backupmgr
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/SteamServerUI/PluginLib"
+)
+
+// Quota policies applied when a new backup would exceed a limit
+const (
+	QuotaPolicyPrune  = "prune"  // delete the oldest backups until the new one fits
+	QuotaPolicyRefuse = "refuse" // skip the new backup and emit a warning
+)
+
+// StorageUsage describes the disk usage of the backup set and the configured limits
+type StorageUsage struct {
+	UsedBytes    int64  `json:"usedBytes"`
+	Backups      int    `json:"backups"`
+	FreeBytes    uint64 `json:"freeBytes"`
+	MaxBytes     int64  `json:"maxBytes,omitempty"`
+	MinFreeBytes int64  `json:"minFreeBytes,omitempty"`
+	Policy       string `json:"policy"`
+}
+
+// Usage returns the current disk usage of the backup set
+func (m *BackupManager) Usage() (StorageUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, err := m.backupSetStatsLocked()
+	if err != nil {
+		return StorageUsage{}, err
+	}
+	free, err := freeDiskSpace(m.config.SafeBackupDir)
+	if err != nil {
+		return StorageUsage{}, fmt.Errorf("failed to read free disk space: %w", err)
+	}
+	return StorageUsage{
+		UsedBytes:    stats.totalBytes,
+		Backups:      stats.count,
+		FreeBytes:    free,
+		MaxBytes:     m.config.QuotaMaxBytes,
+		MinFreeBytes: m.config.QuotaMinFreeBytes,
+		Policy:       m.config.QuotaPolicy,
+	}, nil
+}
+
+// quotaKeepNewest is how many of the newest backups pruning for space always keeps
+const quotaKeepNewest = 2
+
+// ensureSpace checks that incoming bytes fit both the free space reserve on the SafeBackupDir
+// volume and the backup set quota. Depending on the policy it prunes the oldest backups to make
+// room or refuses. The newest quotaKeepNewest backups and pinned ones are never pruned, and if pruning
+// everything else would not make room nothing is deleted. The caller must hold m.mu.
+func (m *BackupManager) ensureSpace(incoming int64) error {
+	if m.config.QuotaMaxBytes <= 0 && m.config.QuotaMinFreeBytes <= 0 {
+		return nil
+	}
+
+	groups, err := m.getBackupGroups()
+	if err != nil {
+		return err
+	}
+	// Oldest first, so pruning removes the oldest backups
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].ModTime.Before(groups[j].ModTime)
+	})
+	var used int64
+	for _, group := range groups {
+		used += groupSize(group)
+	}
+
+	refuse := func(problem string) error {
+		err := fmt.Errorf("not storing backup: %s", problem)
+		PluginLib.Log(fmt.Sprintf("%s %s", m.config.Identifier, err.Error()), "Error")
+		publishEvent(EventQuotaExceeded, m.config.Identifier, problem, map[string]any{"usedBytes": used, "incomingBytes": incoming})
+		return err
+	}
+
+	problem, err := m.spaceProblem(used, incoming, 0)
+	if err != nil || problem == "" {
+		return err
+	}
+	if m.config.QuotaPolicy != QuotaPolicyPrune {
+		return refuse(problem)
+	}
+
+	pinned, err := m.pinnedBackups()
+	if err != nil {
+		return err
+	}
+	keep := min(quotaKeepNewest, len(groups))
+	var prunable []BackupGroup
+	for _, group := range groups[:len(groups)-keep] {
+		if !pinned[group.ID] {
+			prunable = append(prunable, group)
+		}
+	}
+	var prunableBytes int64
+	for _, group := range prunable {
+		prunableBytes += groupSize(group)
+	}
+	remaining, err := m.spaceProblem(used-prunableBytes, incoming, prunableBytes)
+	if err != nil {
+		return err
+	}
+	if remaining != "" {
+		return refuse(fmt.Sprintf("%s, pruning all but the newest %d and the pinned backups would not make enough room", problem, keep))
+	}
+
+	for problem != "" {
+		if len(prunable) == 0 {
+			return refuse(problem)
+		}
+		oldest := prunable[0]
+		prunable = prunable[1:]
+		// Measured before deleting, the files are gone afterwards
+		size := groupSize(oldest)
+		if err := m.deleteBackupGroup(oldest); err != nil {
+			return fmt.Errorf("failed to prune backup %d: %w", oldest.Index, err)
+		}
+		used -= size
+		auditPrune(oldest, problem)
+		PluginLib.Log(fmt.Sprintf("%s Pruned backup %d to stay within the storage limits (%s)", m.config.Identifier, oldest.Index, problem), "Info")
+		publishEvent(EventQuotaExceeded, m.config.Identifier, fmt.Sprintf("Pruned backup %d: %s", oldest.Index, problem), map[string]any{"index": oldest.Index, "usedBytes": used})
+
+		if problem, err = m.spaceProblem(used, incoming, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spaceProblem describes which limit storing incoming bytes would violate, or returns "" if it fits.
+// freed is added to the free disk space, to check limits as if backups had been pruned.
+func (m *BackupManager) spaceProblem(used, incoming, freed int64) (string, error) {
+	if m.config.QuotaMaxBytes > 0 && used+incoming > m.config.QuotaMaxBytes {
+		return fmt.Sprintf("backup quota of %d bytes exceeded (%d used, %d incoming)", m.config.QuotaMaxBytes, used, incoming), nil
+	}
+	if m.config.QuotaMinFreeBytes > 0 {
+		free, err := freeDiskSpace(m.config.SafeBackupDir)
+		if err != nil {
+			return "", fmt.Errorf("failed to read free disk space: %w", err)
+		}
+		if int64(free)+freed-incoming < m.config.QuotaMinFreeBytes {
+			return fmt.Sprintf("only %d bytes free on the backup volume, %d must stay free", free, m.config.QuotaMinFreeBytes), nil
+		}
+	}
+	return "", nil
+}
+
+// groupSize returns the total size of a backup group's files
+func groupSize(group BackupGroup) int64 {
+	var size int64
+	for _, path := range []string{group.BinFile, group.XMLFile, group.MetaFile} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
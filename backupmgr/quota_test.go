@@ -0,0 +1,74 @@
+package backupmgr
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestEnsureSpacePrunes(t *testing.T) {
+	// Five backups of 100 bytes, save1.save is the oldest
+	const backups, size = 5, 100
+	tests := []struct {
+		name     string
+		maxBytes int64
+		policy   string
+		pinned   []string
+		incoming int64
+		wantErr  bool
+		pruned   []string
+	}{
+		{"fits", 600, QuotaPolicyPrune, nil, 100, false, nil},
+		{"oldest pruned", 500, QuotaPolicyPrune, nil, 100, false, []string{"save1.save"}},
+		{"several pruned", 400, QuotaPolicyPrune, nil, 150, false, []string{"save1.save", "save2.save", "save3.save"}},
+		{"pinned kept", 500, QuotaPolicyPrune, []string{"save1.save"}, 100, false, []string{"save2.save"}},
+		{"newest kept", 250, QuotaPolicyPrune, nil, 100, true, nil},
+		{"pinned don't make room", 300, QuotaPolicyPrune, []string{"save1.save", "save2.save"}, 100, true, nil},
+		{"refused", 500, QuotaPolicyRefuse, nil, 100, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t.TempDir(), "Mars")
+			cfg.QuotaMaxBytes = tt.maxBytes
+			cfg.QuotaPolicy = tt.policy
+			m := newTestManager(t, cfg)
+
+			now := time.Now()
+			var names []string
+			for i := 1; i <= backups; i++ {
+				name := fmt.Sprintf("save%d.save", i)
+				path := filepath.Join(cfg.SafeBackupDir, name)
+				if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				modTime := now.Add(time.Duration(i-backups) * time.Hour)
+				if err := os.Chtimes(path, modTime, modTime); err != nil {
+					t.Fatal(err)
+				}
+				names = append(names, name)
+			}
+			for _, id := range tt.pinned {
+				if _, err := m.SetBackupPinned(id, true); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			m.mu.Lock()
+			err := m.ensureSpace(tt.incoming)
+			m.mu.Unlock()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ensureSpace() = %v, want error %v", err, tt.wantErr)
+			}
+
+			for _, name := range names {
+				_, statErr := os.Stat(filepath.Join(cfg.SafeBackupDir, name))
+				if gone := os.IsNotExist(statErr); gone != slices.Contains(tt.pruned, name) {
+					t.Errorf("%s pruned = %v, want %v", name, gone, !gone)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package backupmgr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/SteamServerUI/PluginLib"
+)
+
+// reconcile copies autosaves that were written while no watcher was running (plugin downtime or the
+// Initialize wait) into SafeBackupDir. A file is copied when it has no stored counterpart, or when the
+// stored copy differs in size or content and the autosave is newer. It runs alongside the watcher:
+// files the watcher already picked up are skipped, and m.mu is only held while a file is compared and copied.
+// Files are copied oldest first, so the lineage of the missed autosaves follows the order they were written in.
+func (m *BackupManager) reconcile(identifier string) (int, error) {
+	type candidate struct {
+		path    string
+		modTime time.Time
+	}
+	var candidates []candidate
+	err := filepath.WalkDir(m.config.BackupDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isValidBackupFile(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // removed since it was listed
+		}
+		candidates = append(candidates, candidate{path: path, modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s failed to scan backup dir %s: %w", identifier, m.config.BackupDir, err)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].modTime.Before(candidates[j].modTime)
+	})
+
+	copied := 0
+	for _, c := range candidates {
+		if m.ctx.Err() != nil {
+			return copied, m.ctx.Err()
+		}
+		if m.reconcileFile(identifier, c.path) {
+			copied++
+		}
+	}
+
+	if copied > 0 {
+		m.mu.Lock()
+		m.applyRetention()
+		m.refreshBackupSetStats()
+		m.mu.Unlock()
+	}
+	return copied, nil
+}
+
+// reconcileFile copies a single missed autosave and reports whether it was copied
+func (m *BackupManager) reconcileFile(identifier, srcPath string) bool {
+	// A file with a pending copy is already being handled by the watcher
+	if !m.markPending(srcPath) {
+		return false
+	}
+	defer m.clearPending(srcPath)
+
+	relativePath, err := filepath.Rel(m.config.BackupDir, srcPath)
+	if err != nil {
+		return false
+	}
+	dstPath := filepath.Join(m.config.SafeBackupDir, relativePath)
+
+	m.mu.Lock()
+	needsCopy, _, err := m.needsReconcile(srcPath, dstPath)
+	m.mu.Unlock()
+	if err != nil {
+		PluginLib.Log(fmt.Sprintf("%s Reconcile skipped %s: %s", identifier, relativePath, err.Error()), "Error")
+		return false
+	}
+	if !needsCopy {
+		return false
+	}
+
+	// The game may still be writing an autosave that appeared just before the manager started
+	waitForStableFile(srcPath, m.config.StabilityWindow)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Check again, the stored copy may have changed while waiting
+	needsCopy, existing, err := m.needsReconcile(srcPath, dstPath)
+	if err != nil || !needsCopy {
+		return false
+	}
+	if err := os.MkdirAll(filepath.Dir(dstPath), os.ModePerm); err != nil {
+		m.recordCopyFailure(filepath.Base(srcPath), err)
+		return false
+	}
+	storedPath, err := m.storeBackupFile(srcPath, dstPath)
+	if err != nil {
+		m.recordCopyFailure(filepath.Base(srcPath), err)
+		return false
+	}
+	// Drop the outdated copy if it was stored in another (plain/compressed/encrypted) variant
+	if existing != "" && existing != storedPath {
+		os.Remove(existing)
+	}
+
+	m.recordBackup()
+	m.recordLineage(storedPath)
+	PluginLib.Log(fmt.Sprintf("%s Reconciled missed autosave %s", identifier, relativePath), "Info")
+	publishEvent(EventCopyFinished, identifier, "Missed autosave copied to safe location", map[string]any{"file": filepath.Base(srcPath), "destination": storedPath, "reconciled": true})
+	m.replicate(storedPath)
+	return true
+}
+
+// needsReconcile reports whether srcPath must be copied to dstPath, and which stored variant already exists
+func (m *BackupManager) needsReconcile(srcPath, dstPath string) (bool, string, error) {
+	srcInfo, err := os.Stat(srcPath)
+	if err != nil {
+		return false, "", err
+	}
+
+	existing := ""
+	for _, candidate := range storedVariants(dstPath) {
+		if _, err := os.Stat(candidate); err == nil {
+			existing = candidate
+			break
+		}
+	}
+	if existing == "" {
+		return true, "", nil
+	}
+
+	dstInfo, err := os.Stat(existing)
+	if err != nil {
+		return false, existing, err
+	}
+	if !srcInfo.ModTime().After(dstInfo.ModTime()) {
+		return false, existing, nil
+	}
+	// Sizes of encrypted and compressed copies differ by design, so only plain copies can be compared cheaply
+	if !isEncryptedFile(existing) && !isCompressedFile(existing) && srcInfo.Size() != dstInfo.Size() {
+		return true, existing, nil
+	}
+
+	same, err := m.sameContent(srcPath, existing)
+	if err != nil {
+		return false, existing, err
+	}
+	return !same, existing, nil
+}
+
+// sameContent compares the SHA-256 of an autosave with the plaintext of a stored backup file
+func (m *BackupManager) sameContent(srcPath, storedPath string) (bool, error) {
+	srcHash, err := hashFile(srcPath)
+	if err != nil {
+		return false, err
+	}
+
+	stored, err := m.openBackupReader(storedPath)
+	if err != nil {
+		return false, err
+	}
+	defer stored.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, stored); err != nil {
+		return false, err
+	}
+	return bytes.Equal(srcHash, h.Sum(nil)), nil
+}
+
+// hashFile returns the SHA-256 of a file's contents
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,80 @@
+package backupmgr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReconcileLineage(t *testing.T) {
+	cfg := testConfig(t.TempDir(), "Mars")
+	m := newTestManager(t, cfg)
+
+	// Names sort differently than the autosaves were written, old.save was stored before the downtime
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	autosaves := []struct {
+		name    string
+		minutes int
+	}{
+		{"old.save", 0},
+		{"c.save", 10},
+		{"a.save", 20},
+		{"b.save", 30},
+	}
+	for _, autosave := range autosaves {
+		modTime := base.Add(time.Duration(autosave.minutes) * time.Minute)
+		for _, dir := range []string{cfg.BackupDir, cfg.SafeBackupDir} {
+			if dir == cfg.SafeBackupDir && autosave.name != "old.save" {
+				continue
+			}
+			path := filepath.Join(dir, autosave.name)
+			if err := os.WriteFile(path, []byte(autosave.name), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(path, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	copied, err := m.reconcile(cfg.Identifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied != 3 {
+		t.Errorf("reconciled %d autosaves, want 3", copied)
+	}
+
+	tree, err := m.BackupTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byIndex := make(map[int]LineageNode)
+	for _, node := range tree.Nodes {
+		byIndex[node.Index] = node
+	}
+	parents := make(map[string]string)
+	live := ""
+	for _, node := range tree.Nodes {
+		if node.Parent != nil {
+			parents[node.ID] = byIndex[*node.Parent].ID
+		}
+		if node.Live {
+			live = node.ID
+		}
+	}
+
+	want := map[string]string{"c.save": "old.save", "a.save": "c.save", "b.save": "a.save"}
+	if len(parents) != len(want) {
+		t.Errorf("parents = %v, want %v", parents, want)
+	}
+	for child, parent := range want {
+		if parents[child] != parent {
+			t.Errorf("parent of %s = %q, want %q", child, parents[child], parent)
+		}
+	}
+	if live != "b.save" {
+		t.Errorf("live backup = %q, want b.save", live)
+	}
+}
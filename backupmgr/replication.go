@@ -0,0 +1,32 @@
+package backupmgr
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/SteamServerUI/PluginLib"
+)
+
+// replicate mirrors a stored backup file to every configured replication target, keeping its
+// path relative to SafeBackupDir. Files are copied as stored, so encrypted backups stay encrypted.
+func (m *BackupManager) replicate(storedPath string) {
+	relativePath, err := filepath.Rel(m.config.SafeBackupDir, storedPath)
+	if err != nil {
+		PluginLib.Log(fmt.Sprintf("%s Cannot replicate %s: %s", m.config.Identifier, storedPath, err.Error()), "Error")
+		return
+	}
+
+	for _, target := range m.config.ReplicationTargets {
+		dstPath := filepath.Join(target, relativePath)
+		if err := os.MkdirAll(filepath.Dir(dstPath), os.ModePerm); err != nil {
+			PluginLib.Log(fmt.Sprintf("%s Failed to create replication dir for %s: %s", m.config.Identifier, dstPath, err.Error()), "Error")
+			continue
+		}
+		if err := copyFile(storedPath, dstPath); err != nil {
+			PluginLib.Log(fmt.Sprintf("%s Failed to replicate %s to %s: %s", m.config.Identifier, storedPath, target, err.Error()), "Error")
+			continue
+		}
+		PluginLib.Log(fmt.Sprintf("%s Replicated %s to %s", m.config.Identifier, filepath.Base(storedPath), target), "Debug")
+	}
+}
@@ -2,6 +2,7 @@ package backupmgr
 
 import (
 	"archive/zip"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -13,270 +14,414 @@ import (
 	"github.com/SteamServerUI/PluginLib"
 )
 
-// RestoreBackup restores a backup with the given index
-func (m *BackupManager) RestoreBackup(index int) error {
+// restoreProgress is called with the number of bytes processed so far and the expected total
+type restoreProgress func(done, total int64)
+
+// progressCounter accumulates processed bytes and forwards them to a restoreProgress callback
+type progressCounter struct {
+	done     int64
+	total    int64
+	progress restoreProgress
+}
+
+func (c *progressCounter) add(n int64) {
+	c.done += n
+	if c.done > c.total {
+		// Rewriting world_meta.xml can make the re-zipped data slightly larger than estimated
+		c.total = c.done
+	}
+	if c.progress != nil {
+		c.progress(c.done, c.total)
+	}
+}
+
+// copyWithProgress copies src to dst in chunks, counting bytes and stopping as soon as ctx is canceled
+func copyWithProgress(ctx context.Context, dst io.Writer, src io.Reader, counter *progressCounter) error {
+	buf := make([]byte, 256*1024)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return err
+			}
+			counter.add(int64(n))
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// restoreBackup performs the actual restore of the backup with the given ID, see BackupGroup.ID.
+// Indexes of .save backups shift as backups come and go, the ID pins the backup that was requested.
+// The SafeBackupDir lock is taken for the duration of the restore, m.mu is only held while
+// looking up the backup, so watching continues during long restores. If ctx is canceled the live save
+// is left as it was before the restore started.
+func (m *BackupManager) restoreBackup(ctx context.Context, id string, progress restoreProgress) error {
+	release, err := m.borrowLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	m.mu.Lock()
-	defer m.mu.Unlock()
-	PluginLib.Log(fmt.Sprintf("Restoring backup with index %d", index), "Info")
+	PluginLib.Log(fmt.Sprintf("Restoring backup %s", id), "Info")
 
 	groups, err := m.getBackupGroups()
 	if err != nil {
+		m.mu.Unlock()
 		return fmt.Errorf("failed to get backup groups: %w", err)
 	}
 
 	var targetGroup BackupGroup
 	for _, group := range groups {
-		if group.Index == index {
+		if group.ID == id {
 			targetGroup = group
 			break
 		}
 	}
+	m.mu.Unlock()
 
-	if targetGroup.Index == 0 {
-		return fmt.Errorf("no backup found with index %d", index)
+	if targetGroup.BinFile == "" {
+		return fmt.Errorf("%w: %s no longer exists", ErrBackupNotFound, id)
 	}
 
-	restoredFiles := make(map[string]string)
-
 	// Handle .save file or old-style trio
-	if targetGroup.BinFile != "" && strings.HasSuffix(targetGroup.BinFile, ".save") {
-		// .save file case
-		backupFile := targetGroup.BinFile
-		destFile := filepath.Join("./saves/"+m.config.WorldName, m.config.WorldName+".save")
-
-		// This check was disabled since it was relatively unnecessary and didnt bring much benefit
-
-		// Before restore, check if we have existing .save files in the root saves/WorldName dir
-		//saveDir := filepath.Join("./saves/", m.config.WorldName)
-		//files, err := os.ReadDir(saveDir)
-		//if err != nil {
-		//	return fmt.Errorf("failed to read save directory %s: %w", saveDir, err)
-		//}
-
-		//for _, file := range files {
-		//	if file.IsDir() {
-		//		continue
-		//	}
-		//	if strings.HasSuffix(file.Name(), ".save") {
-		//		existingFile := filepath.Join(saveDir, file.Name())
-		//		// Move existing .save file to SafeBackupDir with timestamp to avoid overwrites
-		//		timestamp := time.Now().Format("2006-01-02_15-04-05")
-		//		savedPreviousHeadSaveFilePath := filepath.Join(m.config.SafeBackupDir, fmt.Sprintf("%s_%s_%s", "pre-restore-HEAD-", timestamp, file.Name()))
-		//		if err := os.Rename(existingFile, savedPreviousHeadSaveFilePath); err != nil {
-		//			return fmt.Errorf("failed to move existing HEAD .save file %s to %s: %w", existingFile, savedPreviousHeadSaveFilePath, err)
-		//		}
-		//		logger.Backup.Info("Moved previous HEAD .save file to: " + savedPreviousHeadSaveFilePath)
-		//	}
-		//}
-
-		// Create temp directory for mod time shenanigans (https://discordapp.com/channels/276525882049429515/392080751648178188/1407157281606336602)
-		tempDir := filepath.Join("./saves", m.config.WorldName, "tmp")
-		if err := os.MkdirAll(tempDir, os.ModePerm); err != nil {
-			return fmt.Errorf("failed to create temp directory %s: %w", tempDir, err)
-		}
-		defer os.RemoveAll(tempDir)
+	if isSaveFile(targetGroup.BinFile) {
+		err = m.restoreSaveFile(ctx, targetGroup.Index, targetGroup.BinFile, progress)
+	} else {
+		err = m.restoreTrio(ctx, targetGroup, progress)
+	}
+	if err != nil {
+		return err
+	}
 
-		// Extract .save (zip) file to tempDir
-		r, err := zip.OpenReader(backupFile)
-		if err != nil {
-			return fmt.Errorf("failed to open zip reader for %s: %w", backupFile, err)
+	// The next backups descend from the restored one, see lineage.go
+	m.mu.Lock()
+	m.markRestored(targetGroup)
+	m.refreshBackupSetStats()
+	m.mu.Unlock()
+	return nil
+}
+
+// restoreSaveFile restores a .save backup. The new save is written next to the live one and only
+// renamed over it once complete, so a failed or canceled restore leaves the live save untouched.
+func (m *BackupManager) restoreSaveFile(ctx context.Context, index int, backupFile string, progress restoreProgress) error {
+	destFile := filepath.Join("./saves/"+m.config.WorldName, m.config.WorldName+".save")
+
+	// This check was disabled since it was relatively unnecessary and didnt bring much benefit
+
+	// Before restore, check if we have existing .save files in the root saves/WorldName dir
+	//saveDir := filepath.Join("./saves/", m.config.WorldName)
+	//files, err := os.ReadDir(saveDir)
+	//if err != nil {
+	//	return fmt.Errorf("failed to read save directory %s: %w", saveDir, err)
+	//}
+
+	//for _, file := range files {
+	//	if file.IsDir() {
+	//		continue
+	//	}
+	//	if strings.HasSuffix(file.Name(), ".save") {
+	//		existingFile := filepath.Join(saveDir, file.Name())
+	//		// Move existing .save file to SafeBackupDir with timestamp to avoid overwrites
+	//		timestamp := time.Now().Format("2006-01-02_15-04-05")
+	//		savedPreviousHeadSaveFilePath := filepath.Join(m.config.SafeBackupDir, fmt.Sprintf("%s_%s_%s", "pre-restore-HEAD-", timestamp, file.Name()))
+	//		if err := os.Rename(existingFile, savedPreviousHeadSaveFilePath); err != nil {
+	//			return fmt.Errorf("failed to move existing HEAD .save file %s to %s: %w", existingFile, savedPreviousHeadSaveFilePath, err)
+	//		}
+	//		logger.Backup.Info("Moved previous HEAD .save file to: " + savedPreviousHeadSaveFilePath)
+	//	}
+	//}
+
+	// Create temp directory for mod time shenanigans (https://discordapp.com/channels/276525882049429515/392080751648178188/1407157281606336602)
+	tempDir := filepath.Join("./saves", m.config.WorldName, "tmp")
+	if err := os.MkdirAll(tempDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create temp directory %s: %w", tempDir, err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Extract .save (zip) file to tempDir, decrypting it first if needed
+	r, closeZip, err := m.openBackupZip(backupFile)
+	if err != nil {
+		return fmt.Errorf("failed to open zip reader for %s: %w", backupFile, err)
+	}
+	defer closeZip()
+
+	// Every byte is processed twice: once when extracting and once when re-zipping
+	counter := &progressCounter{progress: progress}
+	for _, f := range r.File {
+		counter.total += 2 * int64(f.UncompressedSize64)
+	}
+	counter.add(0)
+
+	// --- Safe extraction -------------------------------------------------
+	for i, f := range r.File {
+		publishEvent(EventRestoreProgress, m.config.Identifier, fmt.Sprintf("Extracting %s", f.Name), map[string]any{"index": index, "entry": i + 1, "entries": len(r.File)})
+
+		// Sanitize the entry name – strip any leading / or .. components.
+		entryName := filepath.Clean(f.Name)
+
+		// Skip empty names or names that contain '..' after cleaning.
+		if entryName == "." || entryName == ".." || strings.Contains(entryName, "..") {
+			// This entry would escape the target directory; reject it.
+			PluginLib.Log(fmt.Sprintf("Skipping potentially unsafe zip entry %q", f.Name), "Info")
+			continue
 		}
-		defer r.Close()
-
-		// --- Safe extraction -------------------------------------------------
-		for _, f := range r.File {
-			// Sanitize the entry name – strip any leading / or .. components.
-			entryName := filepath.Clean(f.Name)
-
-			// Skip empty names or names that contain '..' after cleaning.
-			if entryName == "." || entryName == ".." || strings.Contains(entryName, "..") {
-				// This entry would escape the target directory; reject it.
-				PluginLib.Log(fmt.Sprintf("Skipping potentially unsafe zip entry %q", f.Name), "Info")
-				continue
-			}
 
-			destPath := filepath.Join(tempDir, entryName)
-			// Ensure the destination is still inside tempDir.
-			if !strings.HasPrefix(filepath.Clean(destPath), filepath.Clean(tempDir)+string(os.PathSeparator)) {
-				PluginLib.Log(fmt.Sprintf("Skipping zip entry that would escape extraction dir: %q", f.Name), "Info")
-				continue
-			}
+		destPath := filepath.Join(tempDir, entryName)
+		// Ensure the destination is still inside tempDir.
+		if !strings.HasPrefix(filepath.Clean(destPath), filepath.Clean(tempDir)+string(os.PathSeparator)) {
+			PluginLib.Log(fmt.Sprintf("Skipping zip entry that would escape extraction dir: %q", f.Name), "Info")
+			continue
+		}
 
-			if f.FileInfo().IsDir() {
-				if err := os.MkdirAll(destPath, f.Mode()); err != nil {
-					m.revertRestore(restoredFiles)
-					return fmt.Errorf("failed to create directory %s: %w", destPath, err)
-				}
-				continue
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(destPath, f.Mode()); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", destPath, err)
 			}
+			continue
+		}
 
-			// Create any missing parent directories.
-			if err := os.MkdirAll(filepath.Dir(destPath), os.ModePerm); err != nil {
-				m.revertRestore(restoredFiles)
-				return fmt.Errorf("failed to create parent directory for %s: %w", destPath, err)
-			}
+		// Create any missing parent directories.
+		if err := os.MkdirAll(filepath.Dir(destPath), os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create parent directory for %s: %w", destPath, err)
+		}
 
-			outFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-			if err != nil {
-				m.revertRestore(restoredFiles)
-				return fmt.Errorf("failed to create file %s: %w", destPath, err)
-			}
+		outFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+		if err != nil {
+			return fmt.Errorf("failed to create file %s: %w", destPath, err)
+		}
 
-			rc, err := f.Open()
-			if err != nil {
-				outFile.Close()
-				m.revertRestore(restoredFiles)
-				return fmt.Errorf("failed to open file in zip %s: %w", f.Name, err)
-			}
+		rc, err := f.Open()
+		if err != nil {
+			outFile.Close()
+			return fmt.Errorf("failed to open file in zip %s: %w", f.Name, err)
+		}
 
-			if _, err := io.Copy(outFile, rc); err != nil {
-				rc.Close()
-				outFile.Close()
-				m.revertRestore(restoredFiles)
-				return fmt.Errorf("failed to extract file %s: %w", destPath, err)
-			}
+		if err := copyWithProgress(ctx, outFile, rc, counter); err != nil {
 			rc.Close()
 			outFile.Close()
+			return fmt.Errorf("failed to extract file %s: %w", destPath, err)
+		}
+		rc.Close()
+		outFile.Close()
+	}
+	// --------------------------------------------------------------------
+
+	// Update world_meta.xml DateTime with current Windows file time using regex
+	now := time.Now()
+	metaFilePath := filepath.Join(tempDir, "world_meta.xml")
+	if _, err := os.Stat(metaFilePath); err == nil {
+		// Read world_meta.xml
+		data, err := os.ReadFile(metaFilePath)
+		if err != nil {
+			return fmt.Errorf("failed to read world_meta.xml: %w", err)
 		}
-		// --------------------------------------------------------------------
-
-		// Update world_meta.xml DateTime with current Windows file time using regex
-		now := time.Now()
-		metaFilePath := filepath.Join(tempDir, "world_meta.xml")
-		if _, err := os.Stat(metaFilePath); err == nil {
-			// Read world_meta.xml
-			data, err := os.ReadFile(metaFilePath)
-			if err != nil {
-				m.revertRestore(restoredFiles)
-				return fmt.Errorf("failed to read world_meta.xml: %w", err)
-			}
 
-			// Calculate Windows file time
-			const windowsEpochToUnixEpoch = 116444736000000000 // 100-ns intervals from 1601 to 1970
-			windowsFileTime := now.UnixNano()/100 + windowsEpochToUnixEpoch
+		// Calculate Windows file time
+		const windowsEpochToUnixEpoch = 116444736000000000 // 100-ns intervals from 1601 to 1970
+		windowsFileTime := now.UnixNano()/100 + windowsEpochToUnixEpoch
 
-			re, err := regexp.Compile(`<DateTime>\d+</DateTime>`)
-			if err != nil {
-				m.revertRestore(restoredFiles)
-				return fmt.Errorf("failed to compile DateTime regex: %w", err)
-			}
-			newDateTime := fmt.Sprintf("<DateTime>%d</DateTime>", windowsFileTime)
-			updatedData := re.ReplaceAll(data, []byte(newDateTime))
-
-			if !re.Match(data) {
-				PluginLib.Log("Restore: DateTime element not found in world_meta.xml, proceeding without updating. Server might not load correct save.", "Info")
-			} else {
-				if err := os.WriteFile(metaFilePath, updatedData, 0644); err != nil {
-					m.revertRestore(restoredFiles)
-					return fmt.Errorf("failed to write updated world_meta.xml: %w", err)
-				}
-			}
-		} else {
-			PluginLib.Log("world_meta.xml not found in extracted files, proceeding without updating DateTime", "Info")
+		re, err := regexp.Compile(`<DateTime>\d+</DateTime>`)
+		if err != nil {
+			return fmt.Errorf("failed to compile DateTime regex: %w", err)
 		}
+		newDateTime := fmt.Sprintf("<DateTime>%d</DateTime>", windowsFileTime)
+		updatedData := re.ReplaceAll(data, []byte(newDateTime))
 
-		// Modify timestamps of extracted files to current system time
-		if err := filepath.Walk(tempDir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if info.IsDir() {
-				return nil
+		if !re.Match(data) {
+			PluginLib.Log("Restore: DateTime element not found in world_meta.xml, proceeding without updating. Server might not load correct save.", "Info")
+		} else {
+			if err := os.WriteFile(metaFilePath, updatedData, 0644); err != nil {
+				return fmt.Errorf("failed to write updated world_meta.xml: %w", err)
 			}
-			return os.Chtimes(path, now, now)
-		}); err != nil {
-			m.revertRestore(restoredFiles)
-			return fmt.Errorf("failed to modify timestamps in %s: %w", tempDir, err)
 		}
+	} else {
+		PluginLib.Log("world_meta.xml not found in extracted files, proceeding without updating DateTime", "Info")
+	}
 
-		// Create new .save (zip) file at destFile with updated timestamps
-		dest, err := os.Create(destFile)
+	// Modify timestamps of extracted files to current system time
+	if err := filepath.Walk(tempDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			m.revertRestore(restoredFiles)
-			return fmt.Errorf("failed to create destination .save file %s: %w", destFile, err)
+			return err
 		}
-		defer dest.Close()
+		if info.IsDir() {
+			return nil
+		}
+		return os.Chtimes(path, now, now)
+	}); err != nil {
+		return fmt.Errorf("failed to modify timestamps in %s: %w", tempDir, err)
+	}
 
-		w := zip.NewWriter(dest)
-		defer w.Close()
+	// Create new .save (zip) file next to destFile with updated timestamps
+	partialFile := destFile + ".restoring"
+	dest, err := os.Create(partialFile)
+	if err != nil {
+		return fmt.Errorf("failed to create destination .save file %s: %w", partialFile, err)
+	}
+	defer os.Remove(partialFile) // no-op once renamed into place
 
-		if err := filepath.Walk(tempDir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if info.IsDir() {
-				return nil
-			}
-			relPath, err := filepath.Rel(tempDir, path)
-			if err != nil {
-				return fmt.Errorf("failed to get relative path for %s: %w", path, err)
-			}
-			relPath = filepath.ToSlash(relPath)
-
-			// Create zip entry with current system timestamp
-			fw, err := w.CreateHeader(&zip.FileHeader{
-				Name:     relPath,
-				Method:   zip.Deflate,
-				Modified: now,
-			})
-			if err != nil {
-				return fmt.Errorf("failed to create zip entry %s: %w", relPath, err)
-			}
+	w := zip.NewWriter(dest)
+	walkErr := filepath.Walk(tempDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(tempDir, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path for %s: %w", path, err)
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		// Create zip entry with current system timestamp
+		fw, err := w.CreateHeader(&zip.FileHeader{
+			Name:     relPath,
+			Method:   zip.Deflate,
+			Modified: now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create zip entry %s: %w", relPath, err)
+		}
 
-			srcFile, err := os.Open(path)
-			if err != nil {
-				return fmt.Errorf("failed to open file %s: %w", path, err)
-			}
-			defer srcFile.Close()
+		srcFile, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", path, err)
+		}
+		defer srcFile.Close()
 
-			if _, err := io.Copy(fw, srcFile); err != nil {
-				return fmt.Errorf("failed to write file %s to zip: %w", relPath, err)
-			}
-			return nil
-		}); err != nil {
-			m.revertRestore(restoredFiles)
-			return fmt.Errorf("failed to restore .save file %s: %w", backupFile, err)
+		if err := copyWithProgress(ctx, fw, srcFile, counter); err != nil {
+			return fmt.Errorf("failed to write file %s to zip: %w", relPath, err)
 		}
-		restoredFiles[destFile] = backupFile
-		return nil // restore and mod time shenanigans successful, no need to return an error
-	} else {
-		// Old-style trio (world_meta.xml, world.xml, world.bin)
-		files := []struct {
-			backupName    string
-			backupNameAlt string
-			destName      string
-		}{
-			{fmt.Sprintf("world_meta(%d).xml", index), fmt.Sprintf("world_meta(%d)_AutoSave.xml", index), "world_meta.xml"},
-			{fmt.Sprintf("world(%d).xml", index), fmt.Sprintf("world(%d)_AutoSave.xml", index), "world.xml"},
-			{fmt.Sprintf("world(%d).bin", index), fmt.Sprintf("world(%d)_AutoSave.bin", index), "world.bin"},
+		return nil
+	})
+	if walkErr == nil {
+		walkErr = w.Close()
+	}
+	if walkErr == nil {
+		walkErr = dest.Sync()
+	}
+	if err := dest.Close(); walkErr == nil {
+		walkErr = err
+	}
+	if walkErr != nil {
+		return fmt.Errorf("failed to restore .save file %s: %w", backupFile, walkErr)
+	}
+
+	// Last chance to back out before the live save is replaced
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := os.Rename(partialFile, destFile); err != nil {
+		return fmt.Errorf("failed to move restored .save file into place: %w", err)
+	}
+	PluginLib.Log(fmt.Sprintf("Restored %s from %s", destFile, backupFile), "Info")
+	return nil // restore and mod time shenanigans successful, no need to return an error
+}
+
+// restoreTrio restores an old-style trio (world_meta.xml, world.xml, world.bin). The live files are
+// moved aside first and put back if any part of the restore fails or is canceled.
+func (m *BackupManager) restoreTrio(ctx context.Context, group BackupGroup, progress restoreProgress) error {
+	index := group.Index
+	files := []struct {
+		backupName string
+		destName   string
+	}{
+		{group.MetaFile, "world_meta.xml"},
+		{group.XMLFile, "world.xml"},
+		{group.BinFile, "world.bin"},
+	}
+
+	backupFiles := make([]string, len(files))
+	counter := &progressCounter{progress: progress}
+	for i, file := range files {
+		if file.backupName == "" {
+			return fmt.Errorf("failed to restore %s: backup %d has no such file", file.destName, index)
+		}
+		info, err := os.Stat(file.backupName)
+		if err != nil {
+			return fmt.Errorf("failed to restore %s: %w", file.destName, err)
+		}
+		backupFiles[i] = file.backupName
+		counter.total += info.Size()
+	}
+	counter.add(0)
+
+	// restoredFiles maps each live file to the copy it was moved aside to, or "" if it did not exist
+	restoredFiles := make(map[string]string)
+	for i, file := range files {
+		publishEvent(EventRestoreProgress, m.config.Identifier, fmt.Sprintf("Restoring %s", file.destName), map[string]any{"index": index, "entry": i + 1, "entries": len(files)})
+		destFile := filepath.Join("./saves/"+m.config.WorldName, file.destName)
+
+		if err := ctx.Err(); err != nil {
+			m.revertRestore(restoredFiles)
+			return err
 		}
 
-		for _, file := range files {
-			backupFile := filepath.Join(m.config.SafeBackupDir, file.backupName)
-			destFile := filepath.Join("./saves/"+m.config.WorldName, file.destName)
-
-			if err := copyFile(backupFile, destFile); err != nil {
-				// Try alternative name
-				backupFileAlt := filepath.Join(m.config.SafeBackupDir, file.backupNameAlt)
-				if err := copyFile(backupFileAlt, destFile); err != nil {
-					m.revertRestore(restoredFiles)
-					return fmt.Errorf("failed to restore %s: %w", file.backupName, err)
-				}
-				backupFile = backupFileAlt
+		previous := destFile + ".pre-restore"
+		if err := os.Rename(destFile, previous); err != nil {
+			if !os.IsNotExist(err) {
+				m.revertRestore(restoredFiles)
+				return fmt.Errorf("failed to move %s aside: %w", destFile, err)
 			}
-			restoredFiles[destFile] = backupFile
+			previous = ""
+		}
+		restoredFiles[destFile] = previous
+
+		if err := m.restoreBackupFile(ctx, backupFiles[i], destFile, counter); err != nil {
+			m.revertRestore(restoredFiles)
+			return fmt.Errorf("failed to restore %s: %w", file.destName, err)
 		}
 	}
-	PluginLib.Log(fmt.Sprintf("Restored files: %v", restoredFiles), "Info")
 
+	for _, previous := range restoredFiles {
+		if previous != "" {
+			os.Remove(previous)
+		}
+	}
+	PluginLib.Log(fmt.Sprintf("Restored files: %v", backupFiles), "Info")
 	return nil
 }
 
-// revertRestore undoes a failed restore operation
+// restoreBackupFile copies a single stored backup file to dst, decrypting it if needed
+func (m *BackupManager) restoreBackupFile(ctx context.Context, src, dst string, counter *progressCounter) error {
+	reader, err := m.openBackupReader(src)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if err := copyWithProgress(ctx, out, reader, counter); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// revertRestore undoes a failed restore operation by putting the previous live files back
 func (m *BackupManager) revertRestore(restoredFiles map[string]string) {
-	for destFile, backupFile := range restoredFiles {
-		if err := os.Remove(destFile); err == nil {
-			_ = copyFile(backupFile, destFile)
+	for destFile, previous := range restoredFiles {
+		os.Remove(destFile)
+		if previous != "" {
+			if err := os.Rename(previous, destFile); err != nil {
+				PluginLib.Log(fmt.Sprintf("Failed to put %s back after a failed restore: %s", destFile, err.Error()), "Error")
+			}
 		}
 	}
 }
@@ -0,0 +1,79 @@
+package backupmgr
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/SteamServerUI/PluginLib"
+)
+
+// applyRetention deletes backup groups beyond MaxBackups or older than MaxAge, pinned ones are kept.
+// The caller must hold m.mu.
+func (m *BackupManager) applyRetention() {
+	if m.config.MaxBackups <= 0 && m.config.MaxAge <= 0 {
+		return
+	}
+
+	groups, err := m.getBackupGroups()
+	if err != nil {
+		PluginLib.Log(fmt.Sprintf("%s Retention skipped: %s", m.config.Identifier, err.Error()), "Error")
+		return
+	}
+
+	pinned, err := m.pinnedBackups()
+	if err != nil {
+		PluginLib.Log(fmt.Sprintf("%s Retention skipped: %s", m.config.Identifier, err.Error()), "Error")
+		return
+	}
+
+	// Newest first
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].ModTime.After(groups[j].ModTime)
+	})
+
+	// Pinned backups are kept and don't count towards MaxBackups
+	kept := 0
+	for _, group := range groups {
+		if pinned[group.ID] {
+			continue
+		}
+		kept++
+		tooMany := m.config.MaxBackups > 0 && kept > m.config.MaxBackups
+		tooOld := m.config.MaxAge > 0 && time.Since(group.ModTime) > m.config.MaxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := m.deleteBackupGroup(group); err != nil {
+			PluginLib.Log(fmt.Sprintf("%s Failed to delete backup %d during retention: %s", m.config.Identifier, group.Index, err.Error()), "Error")
+			continue
+		}
+		auditPrune(group, "retention")
+		PluginLib.Log(fmt.Sprintf("%s Retention removed backup %d from %s", m.config.Identifier, group.Index, group.ModTime.Format(time.RFC3339)), "Info")
+	}
+}
+
+// deleteBackupGroup removes all files belonging to a backup group and its catalog entry.
+// The caller must hold m.mu.
+func (m *BackupManager) deleteBackupGroup(group BackupGroup) error {
+	for _, path := range []string{group.BinFile, group.XMLFile, group.MetaFile} {
+		if path == "" {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if group.BinFile != "" {
+		os.Remove(thumbnailCachePath(group.BinFile))
+	}
+
+	// A later backup may reuse the file name, so it must not inherit the metadata
+	key := m.catalogKey(group)
+	err := m.updateCatalog(func(catalog *backupCatalog) bool { return catalog.remove(key) })
+	if err != nil {
+		PluginLib.Log(fmt.Sprintf("%s Failed to remove backup %d from the catalog: %s", m.config.Identifier, group.Index, err.Error()), "Error")
+	}
+	return nil
+}
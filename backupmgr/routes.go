@@ -0,0 +1,185 @@
+package backupmgr
+
+import "net/http"
+
+// Content types of route responses
+const (
+	contentJSON   = "application/json"
+	contentText   = "text/plain"
+	contentStream = "text/event-stream"
+	contentBinary = "application/octet-stream"
+	contentImage  = "image/*"
+)
+
+// Route is an HTTP route of the plugin API. The route table is used both to register the
+// handlers and to generate the OpenAPI document, so the two cannot drift apart.
+type Route struct {
+	Path       string
+	Handler    http.HandlerFunc
+	Operations []Operation
+	// Summary describes routes without operations, like catch-alls that answer any method
+	Summary string
+}
+
+// Operation describes one HTTP method of a route for the API document
+type Operation struct {
+	Method      string
+	Summary     string
+	Scope       string // API token scope required to call the operation, see auth.go
+	Audit       string // action recorded in the audit log, empty for operations that change nothing
+	Query       []Param
+	RequestBody string // description of the JSON request body, empty if the operation takes none
+	Status      int    // status code of a successful response, defaults to 200
+	Response    string // content type of a successful response, empty if it has no body
+	// QueryToken accepts the API token as access_token query parameter, for clients that can't
+	// set headers like EventSource and <img>. Tokens in URLs end up in logs, so only where needed.
+	QueryToken bool
+}
+
+// Param is a query parameter of an operation
+type Param struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// Routes returns the route table of the plugin API, excluding the UI assets
+func (h *HTTPHandler) Routes() []Route {
+	return []Route{
+		// v1
+		{Path: "/api/v1/backups", Handler: h.ListBackupsHandler, Operations: []Operation{{
+			Method: http.MethodGet, Scope: ScopeRead, Summary: "List backups, newest first", Response: contentJSON,
+			Query: []Param{
+				{Name: "limit", Description: "Number of backups to return, 0 for all"},
+				{Name: "mode", Description: "Set to classic for the plain text listing"},
+			},
+		}}},
+		{Path: "/api/v1/backups/restore", Handler: h.RestoreBackupHandler, Operations: []Operation{{
+			Method: http.MethodGet, Scope: ScopeRestore, Audit: "restore", Summary: "Restore a backup and wait for it to finish", Response: contentText,
+			Query: []Param{
+				{Name: "index", Description: "Index of the backup to restore", Required: true},
+				{Name: "async", Description: "1 to only queue the restore and respond with 202 and the v2 job in the Location header"},
+			},
+		}}},
+		{Path: "/api/v1/backups/download", Handler: h.DownloadBackupHandler, Operations: []Operation{{
+			Method: http.MethodGet, Scope: ScopeRead, Summary: "Download a backup, decrypted", Response: contentBinary,
+			Query: []Param{{Name: "index", Description: "Index of the backup to download", Required: true}},
+		}}},
+		{Path: "/api/v1/backups/diff", Handler: h.DiffBackupsHandler, Operations: []Operation{{
+			Method: http.MethodGet, Scope: ScopeRead, Summary: "Compare the files, world_meta.xml fields and world.xml structure of two backups", Response: contentJSON,
+			Query: []Param{
+				{Name: "a", Description: "Index of the older backup", Required: true},
+				{Name: "b", Description: "Index of the newer backup", Required: true},
+			},
+		}}},
+		{Path: "/api/v1/backups/{id}/inspect", Handler: h.InspectBackupHandler, Operations: []Operation{{
+			Method: http.MethodGet, Scope: ScopeRead, Summary: "Count the structures, things, atmospheres, rooms and players in a backup's world", Response: contentJSON,
+		}}},
+		{Path: "/api/v1/backups/{id}/thumbnail", Handler: h.ThumbnailHandler, Operations: []Operation{{
+			Method: http.MethodGet, Scope: ScopeRead, Summary: "Get the screenshot stored in a .save backup", Response: contentImage,
+			QueryToken: true,
+		}}},
+		{Path: "/api/v1/backups/{id}/convert", Handler: h.ConvertBackupHandler, Operations: []Operation{{
+			Method: http.MethodPost, Scope: ScopeSnapshot, Audit: "backup.convert", Summary: "Convert a legacy trio to a .save backup or back, keeping the source", Status: http.StatusCreated, Response: contentJSON,
+		}}},
+		{Path: "/api/v1/backups/export", Handler: h.ExportBundleHandler, Operations: []Operation{{
+			Method: http.MethodGet, Scope: ScopeRead, Summary: "Download backups as a portable bundle with a manifest, decrypted", Response: contentBinary,
+			Query: []Param{{Name: "index", Description: "Comma separated indexes of the backups to export", Required: true}},
+		}}},
+		{Path: "/api/v1/backups/import", Handler: h.ImportBundleHandler, Operations: []Operation{{
+			Method: http.MethodPost, Scope: ScopeAdmin, Audit: "backup.import", Summary: "Store the backups of an exported bundle after checking its hashes", Status: http.StatusCreated, Response: contentJSON,
+			RequestBody: "Bundle archive as produced by /api/v1/backups/export",
+		}}},
+		{Path: "/api/v1/backups/tree", Handler: h.BackupTreeHandler, Operations: []Operation{{
+			Method: http.MethodGet, Scope: ScopeRead, Summary: "Get the lineage of all backups and the branch the live save is on", Response: contentJSON,
+		}}},
+		{Path: "/api/v1/backups/rotate-key", Handler: h.RotateKeyHandler, Operations: []Operation{{
+			Method: http.MethodPost, Scope: ScopeAdmin, Audit: "encryption.rotate_key", Summary: "Re-encrypt all backups with a new passphrase or key file", Response: contentText,
+			RequestBody: "{\"passphrase\": string, \"keyFile\": string}",
+		}}},
+		{Path: "/api/v1/backups/snapshot", Handler: h.SnapshotHandler, Operations: []Operation{{
+			Method: http.MethodPost, Scope: ScopeSnapshot, Audit: "snapshot", Summary: "Take a snapshot of the live save", Response: contentJSON,
+		}}},
+		{Path: "/api/v1/events", Handler: h.EventsHandler, Operations: []Operation{{
+			Method: http.MethodGet, Scope: ScopeRead, Summary: "Stream backup manager events as Server-Sent Events", Response: contentStream,
+			QueryToken: true,
+		}}},
+		{Path: "/api/v1/webhooks/test", Handler: h.TestWebhooksHandler, Operations: []Operation{{
+			Method: http.MethodPost, Scope: ScopeAdmin, Audit: "webhook.test", Summary: "Send a test event to all configured webhooks", Response: contentJSON,
+		}}},
+		{Path: "/api/v1/status", Handler: h.StatusHandler, Operations: []Operation{{
+			Method: http.MethodGet, Scope: ScopeRead, Summary: "Get the lifecycle state of the backup manager", Response: contentJSON,
+		}}},
+		{Path: "/api/v1/usage", Handler: h.UsageHandler, Operations: []Operation{{
+			Method: http.MethodGet, Scope: ScopeRead, Summary: "Get the disk usage of the backup set and the storage limits", Response: contentJSON,
+		}}},
+		{Path: "/api/v1/config", Handler: h.ConfigHandler, Operations: []Operation{
+			{Method: http.MethodGet, Scope: ScopeAdmin, Summary: "Get the plugin settings with secrets redacted", Response: contentJSON},
+			{Method: http.MethodPut, Scope: ScopeAdmin, Audit: "config.update", Summary: "Validate, store and apply plugin settings", Response: contentJSON, RequestBody: "Settings document, omitted fields keep their current value"},
+		}},
+		{Path: "/api/v1/audit", Handler: h.AuditHandler, Operations: []Operation{{
+			Method: http.MethodGet, Scope: ScopeAdmin, Summary: "Query the audit log, newest first", Response: contentJSON,
+			Query: []Param{
+				{Name: "action", Description: "Only entries of this action or its sub-actions, e.g. restore"},
+				{Name: "actor", Description: "Only entries of this token name"},
+				{Name: "outcome", Description: "Only entries with this outcome: success, failure or denied"},
+				{Name: "since", Description: "Only entries at or after this RFC 3339 time"},
+				{Name: "until", Description: "Only entries at or before this RFC 3339 time"},
+				{Name: "limit", Description: "Maximum number of entries, defaults to 100"},
+			},
+		}}},
+		{Path: "/metrics", Handler: h.MetricsHandler, Operations: []Operation{{
+			Method: http.MethodGet, Scope: ScopeRead, Summary: "Prometheus metrics", Response: contentText,
+		}}},
+
+		// v2
+		{Path: "/api/v2/", Handler: h.NotFoundV2Handler, Summary: "Answers unknown v2 routes with a not_found error"},
+		{Path: "/api/v2/backups", Handler: h.BackupsV2Handler, Operations: []Operation{{
+			Method: http.MethodGet, Scope: ScopeRead, Summary: "List backups, newest first, one page at a time", Response: contentJSON,
+			Query: []Param{
+				{Name: "limit", Description: "Page size, 1 to 500, defaults to 50"},
+				{Name: "cursor", Description: "nextCursor of the previous page"},
+				{Name: "since", Description: "Only backups created at or after this RFC 3339 time"},
+				{Name: "until", Description: "Only backups created at or before this RFC 3339 time"},
+				{Name: "type", Description: "Only backups of this type, save or trio"},
+				{Name: "tag", Description: "Only backups with this tag, repeat to require several tags"},
+			},
+		}}},
+		{Path: "/api/v2/backups/{id}", Handler: h.BackupV2Handler, Operations: []Operation{
+			{Method: http.MethodGet, Scope: ScopeRead, Summary: "Get a backup", Response: contentJSON},
+			{
+				Method: http.MethodPatch, Scope: ScopeSnapshot, Audit: "backup.update", Summary: "Change the notes and tags of a backup", Response: contentJSON,
+				RequestBody: "{\"notes\": string, \"tags\": [string]}",
+			},
+			{Method: http.MethodDelete, Scope: ScopeDelete, Audit: "backup.delete", Summary: "Delete a backup", Status: http.StatusNoContent},
+		}},
+		{Path: "/api/v2/backups/{id}/pin", Handler: h.PinV2Handler, Operations: []Operation{
+			{Method: http.MethodPut, Scope: ScopeDelete, Audit: "backup.pin", Summary: "Pin a backup so retention and the storage quota keep it", Response: contentJSON},
+			{Method: http.MethodDelete, Scope: ScopeDelete, Audit: "backup.unpin", Summary: "Unpin a backup", Response: contentJSON},
+		}},
+		{Path: "/api/v2/snapshots", Handler: h.SnapshotsV2Handler, Operations: []Operation{{
+			Method: http.MethodPost, Scope: ScopeSnapshot, Audit: "snapshot", Summary: "Take a snapshot of the live save", Status: http.StatusCreated, Response: contentJSON,
+		}}},
+		{Path: "/api/v2/restores", Handler: h.RestoresV2Handler, Operations: []Operation{{
+			Method: http.MethodPost, Scope: ScopeRestore, Audit: "restore", Summary: "Queue a restore of a backup", Status: http.StatusAccepted, Response: contentJSON,
+			RequestBody: "{\"backupId\": string}",
+		}}},
+		{Path: "/api/v2/jobs/{id}", Handler: h.JobV2Handler, Operations: []Operation{{
+			Method: http.MethodGet, Scope: ScopeRead, Summary: "Get the state and progress of a job", Response: contentJSON,
+		}}},
+		{Path: "/api/v2/jobs/{id}/cancel", Handler: h.CancelJobV2Handler, Operations: []Operation{{
+			Method: http.MethodPost, Scope: ScopeRestore, Audit: "job.cancel", Summary: "Cancel a queued or running job", Status: http.StatusAccepted, Response: contentJSON,
+		}}},
+
+		{Path: "/api/openapi.json", Handler: h.OpenAPIHandler, Operations: []Operation{{
+			Method: http.MethodGet, Scope: ScopeRead, Summary: "This OpenAPI document", Response: contentJSON,
+		}}},
+	}
+}
+
+// Register registers every route of the plugin API, guarded by token authorization
+func (h *HTTPHandler) Register(register func(path string, handler http.HandlerFunc)) {
+	for _, route := range h.Routes() {
+		register(route.Path, authorize(route))
+	}
+}
@@ -0,0 +1,186 @@
+package backupmgr
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SteamServerUI/PluginLib"
+)
+
+// snapshotStabilityWindow is the shortest time the live save must stay unchanged before a snapshot copies it
+const snapshotStabilityWindow = 2 * time.Second
+
+// snapshotIndexBase keeps the indices of scheduled legacy trio snapshots clear of the game's own autosave numbering
+const snapshotIndexBase = 100000
+
+// scheduleEnabled reports whether scheduled snapshots are configured
+func (c BackupConfig) scheduleEnabled() bool {
+	return c.ScheduleInterval > 0 || c.ScheduleCron != ""
+}
+
+// runScheduler takes snapshots of the live save on the configured interval or cron schedule until the
+// manager's context is canceled. The caller must have added the goroutine to m.wg.
+func (m *BackupManager) runScheduler(identifier string) {
+	defer m.wg.Done()
+
+	var cron *cronSchedule
+	if m.config.ScheduleCron != "" {
+		var err error
+		if cron, err = parseCron(m.config.ScheduleCron); err != nil {
+			PluginLib.Log(fmt.Sprintf("%s Scheduled backups disabled: %s", identifier, err.Error()), "Error")
+			return
+		}
+	}
+
+	PluginLib.Log(fmt.Sprintf("%s Scheduled backups enabled", identifier), "Info")
+	for {
+		wait := m.config.ScheduleInterval
+		if cron != nil {
+			next, ok := cron.next(time.Now())
+			if !ok {
+				PluginLib.Log(fmt.Sprintf("%s Cron expression %q never matches, scheduled backups stopped", identifier, m.config.ScheduleCron), "Error")
+				return
+			}
+			wait = time.Until(next)
+		}
+
+		select {
+		case <-m.ctx.Done():
+			PluginLib.Log(fmt.Sprintf("%s Scheduler stopped due to context cancellation", identifier), "Debug")
+			return
+		case <-time.After(wait):
+		}
+
+		if m.config.ScheduleOnlyWhenRunning {
+			status, err := PluginLib.GetServerStatus()
+			if err != nil || !status.Status {
+				PluginLib.Log(fmt.Sprintf("%s Skipping scheduled backup, gameserver is not running", identifier), "Debug")
+				continue
+			}
+		}
+
+		if _, err := m.Snapshot(); err != nil {
+			PluginLib.Log(fmt.Sprintf("%s Scheduled backup failed: %s", identifier, err.Error()), "Error")
+		}
+	}
+}
+
+// Snapshot copies the live head save from SaveDir into SafeBackupDir and returns the stored files.
+// The game may be writing the save, so it is copied once it stopped changing.
+func (m *BackupManager) Snapshot() ([]string, error) {
+	sources, err := m.headSaveFiles()
+	if err == nil {
+		for _, src := range sources {
+			waitForStableFile(src, max(m.config.StabilityWindow, snapshotStabilityWindow))
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err != nil {
+		m.recordCopyFailure("snapshot", err)
+		return nil, err
+	}
+
+	timestamp := time.Now().Format("2006-01-02_15-04-05")
+	var destinations []string
+	if len(sources) == 1 {
+		destinations = []string{filepath.Join(m.config.SafeBackupDir, fmt.Sprintf("snapshot_%s_%s", timestamp, filepath.Base(sources[0])))}
+	} else {
+		index, err := m.nextSnapshotIndex()
+		if err != nil {
+			return nil, err
+		}
+		destinations = []string{
+			filepath.Join(m.config.SafeBackupDir, fmt.Sprintf("world(%d).xml", index)),
+			filepath.Join(m.config.SafeBackupDir, fmt.Sprintf("world_meta(%d).xml", index)),
+			filepath.Join(m.config.SafeBackupDir, fmt.Sprintf("world(%d).bin", index)),
+		}
+	}
+
+	if err := os.MkdirAll(m.config.SafeBackupDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create safe backup dir: %w", err)
+	}
+
+	var stored []string
+	for i, src := range sources {
+		storedPath, err := m.storeBackupFile(src, destinations[i])
+		if err != nil {
+			for _, path := range stored {
+				os.Remove(path)
+			}
+			m.recordCopyFailure(filepath.Base(src), err)
+			return nil, fmt.Errorf("failed to snapshot %s: %w", src, err)
+		}
+		stored = append(stored, storedPath)
+	}
+
+	m.recordBackup()
+	for _, path := range stored {
+		m.recordLineage(path)
+	}
+	PluginLib.Log(fmt.Sprintf("%s Snapshot of the live save stored: %s", m.config.Identifier, strings.Join(stored, ", ")), "Info")
+	publishEvent(EventSnapshotCreated, m.config.Identifier, "Snapshot of the live save stored", map[string]any{"files": stored})
+	for _, path := range stored {
+		m.replicate(path)
+	}
+	m.applyRetention()
+	m.refreshBackupSetStats()
+	return stored, nil
+}
+
+// headSaveFiles returns the files making up the live save: the newest .save archive directly
+// in SaveDir, or the legacy world.xml, world_meta.xml and world.bin trio.
+func (m *BackupManager) headSaveFiles() ([]string, error) {
+	entries, err := os.ReadDir(m.config.SaveDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read save dir %s: %w", m.config.SaveDir, err)
+	}
+
+	var saves []os.DirEntry
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".save") {
+			saves = append(saves, entry)
+		}
+	}
+	if len(saves) > 0 {
+		sort.Slice(saves, func(i, j int) bool {
+			a, errA := saves[i].Info()
+			b, errB := saves[j].Info()
+			return errA == nil && errB == nil && a.ModTime().After(b.ModTime())
+		})
+		return []string{filepath.Join(m.config.SaveDir, saves[0].Name())}, nil
+	}
+
+	trio := []string{
+		filepath.Join(m.config.SaveDir, "world.xml"),
+		filepath.Join(m.config.SaveDir, "world_meta.xml"),
+		filepath.Join(m.config.SaveDir, "world.bin"),
+	}
+	for _, path := range trio {
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("no live save found in %s", m.config.SaveDir)
+		}
+	}
+	return trio, nil
+}
+
+// nextSnapshotIndex returns the index for a new legacy trio snapshot. The caller must hold m.mu.
+func (m *BackupManager) nextSnapshotIndex() (int, error) {
+	groups, err := m.getBackupGroups()
+	if err != nil {
+		return 0, err
+	}
+	next := snapshotIndexBase
+	for _, group := range groups {
+		if !isSaveFile(group.BinFile) && group.Index >= next {
+			next = group.Index + 1
+		}
+	}
+	return next, nil
+}
@@ -0,0 +1,512 @@
+package backupmgr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SteamServerUI/PluginLib"
+)
+
+// settingsKey is the SSUI setting the plugin configuration is stored under, as a JSON document
+const settingsKey = "BackupManagerSettings"
+
+// redactedSecret replaces secrets in API responses; sending it back keeps the stored value
+const redactedSecret = "********"
+
+// Duration is a time.Duration that is stored as a human readable string like "20s" or "1h30m"
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\": %w", err)
+	}
+	if str == "" {
+		*d = 0
+		return nil
+	}
+	parsed, err := time.ParseDuration(str)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Settings is the persisted, user editable plugin configuration
+type Settings struct {
+	// WaitTime is how long to wait after a new autosave appears before copying it
+	WaitTime Duration `json:"waitTime"`
+	// StabilityWindow additionally requires the file to stay unchanged for this long before it is copied
+	StabilityWindow Duration `json:"stabilityWindow"`
+
+	Watcher            WatcherSettings   `json:"watcher"`
+	Schedule           ScheduleSettings  `json:"schedule"`
+	Retention          RetentionSettings `json:"retention"`
+	Quota              QuotaSettings     `json:"quota"`
+	ReplicationTargets []string          `json:"replicationTargets"`
+	// Compression is "none" (default) or "gzip" to store legacy trio files gzipped, see compression.go
+	Compression string             `json:"compression"`
+	Encryption  EncryptionSettings `json:"encryption"`
+	Webhooks    []WebhookTarget    `json:"webhooks"`
+	Auth        AuthSettings       `json:"auth"`
+}
+
+// WatcherSettings selects how new autosaves are detected
+type WatcherSettings struct {
+	// Mode is "auto" (fsnotify with polling fallback), "fsnotify" or "poll"
+	Mode         string   `json:"mode"`
+	PollInterval Duration `json:"pollInterval"`
+}
+
+// ScheduleSettings configures snapshots of the live save independent of the game's autosaves.
+// At most one of Interval and Cron may be set; neither disables scheduled snapshots.
+type ScheduleSettings struct {
+	Interval        Duration `json:"interval"`
+	Cron            string   `json:"cron"`
+	OnlyWhenRunning bool     `json:"onlyWhenRunning"`
+}
+
+// RetentionSettings limits how many backups are kept in SafeBackupDir. Zero values disable a limit.
+type RetentionSettings struct {
+	MaxBackups int      `json:"maxBackups"`
+	MaxAge     Duration `json:"maxAge"`
+}
+
+// QuotaSettings limits the disk space used by backups. Zero values disable a limit.
+type QuotaSettings struct {
+	MaxBytes     int64  `json:"maxBytes"`
+	MinFreeBytes int64  `json:"minFreeBytes"`
+	Policy       string `json:"policy"` // "prune" (default) or "refuse"
+}
+
+// EncryptionSettings configures at-rest encryption, see encryption.go
+type EncryptionSettings struct {
+	Passphrase string `json:"passphrase,omitempty"`
+	KeyFile    string `json:"keyFile,omitempty"`
+}
+
+// AuthSettings configures access to the plugin API, see auth.go. Without tokens the API is open.
+type AuthSettings struct {
+	Tokens []APIToken `json:"tokens"`
+	// TrustedProxies lists the addresses or CIDR ranges of reverse proxies in front of SSUI. Only
+	// requests from them may name the client in X-Forwarded-For or X-Real-IP, see sourceIP.
+	TrustedProxies []string `json:"trustedProxies,omitempty"`
+}
+
+// SettingsValidationError lists every problem found in a settings document
+type SettingsValidationError struct {
+	Problems []string `json:"problems"`
+}
+
+func (e *SettingsValidationError) Error() string {
+	return "invalid settings: " + strings.Join(e.Problems, "; ")
+}
+
+// DefaultSettings returns the settings used when nothing has been saved yet
+func DefaultSettings() Settings {
+	return Settings{
+		WaitTime: Duration(20 * time.Second),
+		Watcher: WatcherSettings{
+			Mode:         WatcherModeAuto,
+			PollInterval: Duration(defaultPollInterval),
+		},
+		Quota: QuotaSettings{
+			Policy: QuotaPolicyPrune,
+		},
+		Compression: CompressionNone,
+	}
+}
+
+// Validate checks the settings and returns a *SettingsValidationError describing all problems
+func (s Settings) Validate() error {
+	var problems []string
+
+	if time.Duration(s.WaitTime) < time.Second || time.Duration(s.WaitTime) > time.Hour {
+		problems = append(problems, "waitTime must be between 1s and 1h")
+	}
+	if s.StabilityWindow < 0 || time.Duration(s.StabilityWindow) > 10*time.Minute {
+		problems = append(problems, "stabilityWindow must be between 0s and 10m")
+	}
+	switch s.Watcher.Mode {
+	case WatcherModeAuto, WatcherModeFsnotify, WatcherModePoll:
+	default:
+		problems = append(problems, fmt.Sprintf("watcher.mode must be %q, %q or %q", WatcherModeAuto, WatcherModeFsnotify, WatcherModePoll))
+	}
+	if time.Duration(s.Watcher.PollInterval) < time.Second || time.Duration(s.Watcher.PollInterval) > 10*time.Minute {
+		problems = append(problems, "watcher.pollInterval must be between 1s and 10m")
+	}
+	if s.Schedule.Interval != 0 && s.Schedule.Cron != "" {
+		problems = append(problems, "schedule.interval and schedule.cron are mutually exclusive")
+	}
+	if s.Schedule.Interval != 0 && time.Duration(s.Schedule.Interval) < time.Minute {
+		problems = append(problems, "schedule.interval must be at least 1m")
+	}
+	if s.Schedule.Cron != "" {
+		if _, err := parseCron(s.Schedule.Cron); err != nil {
+			problems = append(problems, "schedule.cron: "+err.Error())
+		}
+	}
+	if s.Retention.MaxBackups < 0 {
+		problems = append(problems, "retention.maxBackups must not be negative")
+	}
+	if s.Retention.MaxAge < 0 {
+		problems = append(problems, "retention.maxAge must not be negative")
+	}
+	if s.Quota.MaxBytes < 0 || s.Quota.MinFreeBytes < 0 {
+		problems = append(problems, "quota.maxBytes and quota.minFreeBytes must not be negative")
+	}
+	if s.Quota.Policy != QuotaPolicyPrune && s.Quota.Policy != QuotaPolicyRefuse {
+		problems = append(problems, fmt.Sprintf("quota.policy must be %q or %q", QuotaPolicyPrune, QuotaPolicyRefuse))
+	}
+	for i, target := range s.ReplicationTargets {
+		if strings.TrimSpace(target) == "" {
+			problems = append(problems, fmt.Sprintf("replicationTargets[%d] must not be empty", i))
+		}
+	}
+	if s.Compression != CompressionNone && s.Compression != CompressionGzip {
+		problems = append(problems, fmt.Sprintf("compression must be %q or %q", CompressionNone, CompressionGzip))
+	}
+	problems = append(problems, s.Encryption.problems()...)
+	for i, hook := range s.Webhooks {
+		u, err := url.Parse(hook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("webhooks[%d].url must be an http(s) URL", i))
+		}
+		if hook.Format != "" && hook.Format != WebhookFormatJSON && hook.Format != WebhookFormatDiscord {
+			problems = append(problems, fmt.Sprintf("webhooks[%d].format must be %q or %q", i, WebhookFormatJSON, WebhookFormatDiscord))
+		}
+		for _, eventType := range hook.Events {
+			if !isKnownEventType(eventType) {
+				problems = append(problems, fmt.Sprintf("webhooks[%d].events contains unknown event %q", i, eventType))
+			}
+		}
+	}
+	problems = append(problems, s.Auth.problems()...)
+
+	if len(problems) > 0 {
+		return &SettingsValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// problems lists what is wrong with the encryption settings
+func (e EncryptionSettings) problems() []string {
+	if e.Passphrase != "" && e.KeyFile != "" {
+		return []string{"encryption.passphrase and encryption.keyFile are mutually exclusive"}
+	}
+	return nil
+}
+
+// problems lists what is wrong with the auth settings
+func (a AuthSettings) problems() []string {
+	var problems []string
+	names := make(map[string]bool)
+	hasAdmin := false
+	for i, token := range a.Tokens {
+		if strings.TrimSpace(token.Name) == "" || names[token.Name] {
+			problems = append(problems, fmt.Sprintf("auth.tokens[%d].name must be set and unique", i))
+		}
+		names[token.Name] = true
+		if len(token.Token) < minTokenLength {
+			problems = append(problems, fmt.Sprintf("auth.tokens[%d].token must be at least %d characters", i, minTokenLength))
+		}
+		if len(token.Scopes) == 0 {
+			problems = append(problems, fmt.Sprintf("auth.tokens[%d].scopes must not be empty", i))
+		}
+		for _, scope := range token.Scopes {
+			if !isKnownScope(scope) {
+				problems = append(problems, fmt.Sprintf("auth.tokens[%d].scopes contains unknown scope %q", i, scope))
+			}
+		}
+		hasAdmin = hasAdmin || token.allows(ScopeAdmin)
+	}
+	// Without an admin token the settings could no longer be changed through the API
+	if len(a.Tokens) > 0 && !hasAdmin {
+		problems = append(problems, "auth.tokens must include at least one token with the admin scope")
+	}
+	for i, entry := range a.TrustedProxies {
+		if _, err := parseTrustedProxy(entry); err != nil {
+			problems = append(problems, fmt.Sprintf("auth.trustedProxies[%d] must be an IP address or CIDR range", i))
+		}
+	}
+	return problems
+}
+
+// Redacted returns a copy of the settings with all secrets replaced by redactedSecret
+func (s Settings) Redacted() Settings {
+	if s.Encryption.Passphrase != "" {
+		s.Encryption.Passphrase = redactedSecret
+	}
+	s.Webhooks = append([]WebhookTarget(nil), s.Webhooks...)
+	for i := range s.Webhooks {
+		if s.Webhooks[i].Secret != "" {
+			s.Webhooks[i].Secret = redactedSecret
+		}
+	}
+	s.Auth.Tokens = append([]APIToken(nil), s.Auth.Tokens...)
+	for i := range s.Auth.Tokens {
+		s.Auth.Tokens[i].Token = redactedSecret
+	}
+	return s
+}
+
+// unredact restores secrets that were sent back as redactedSecret from the current settings
+func (s *Settings) unredact(current Settings) {
+	if s.Encryption.Passphrase == redactedSecret {
+		s.Encryption.Passphrase = current.Encryption.Passphrase
+	}
+	for i := range s.Webhooks {
+		if s.Webhooks[i].Secret != redactedSecret {
+			continue
+		}
+		s.Webhooks[i].Secret = ""
+		for _, old := range current.Webhooks {
+			if old.URL == s.Webhooks[i].URL {
+				s.Webhooks[i].Secret = old.Secret
+				break
+			}
+		}
+	}
+	for i := range s.Auth.Tokens {
+		if s.Auth.Tokens[i].Token != redactedSecret {
+			continue
+		}
+		s.Auth.Tokens[i].Token = ""
+		for _, old := range current.Auth.Tokens {
+			if old.Name == s.Auth.Tokens[i].Name {
+				s.Auth.Tokens[i].Token = old.Token
+				break
+			}
+		}
+	}
+}
+
+var (
+	settingsMu      sync.Mutex
+	currentSettings *Settings
+	// loadedSettingsRaw is the stored settings document last read or saved, used to detect external changes
+	loadedSettingsRaw string
+	// settingsErr is why the stored settings document was rejected, nil if it is in use
+	settingsErr error
+	// settingsSalvaged is set while no valid document was loaded yet and the settings in use were
+	// salvaged from an invalid one, settingsLocked if even that failed, see salvageSettings
+	settingsSalvaged bool
+	settingsLocked   bool
+)
+
+// ErrSettingsLocked is returned while the stored settings are too broken to run on safely.
+// The API refuses every request and no backup manager runs until the document is fixed in SSUI.
+var ErrSettingsLocked = errors.New("settings are locked until the stored settings document is fixed")
+
+// GetSettings returns the current plugin settings, loading them from SSUI on first use.
+// While the stored document is invalid the last valid settings stay in use; if there are none
+// the settings are salvaged from the invalid document, see salvageSettings.
+func GetSettings() Settings {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	if currentSettings == nil {
+		raw := getOptionalStringSetting(settingsKey)
+		loaded, err := parseSettings(raw)
+		loadedSettingsRaw = raw
+		settingsErr = err
+		if err != nil {
+			loaded, settingsLocked = salvageSettings(raw)
+			settingsSalvaged = true
+			PluginLib.Log(fmt.Sprintf("Invalid %s setting, running on the default settings: %s", settingsKey, err.Error()), "Error")
+		}
+		currentSettings = &loaded
+	}
+	return *currentSettings
+}
+
+// salvageSettings builds the settings to run on when the stored document is invalid and no valid
+// one was loaded before: the defaults, plus the auth and encryption sections of the document when
+// they are valid on their own. A typo elsewhere must neither open the API nor store backups
+// unencrypted, so it reports the settings as locked if either section can't be recovered.
+func salvageSettings(raw string) (Settings, bool) {
+	settings := DefaultSettings()
+	var sections struct {
+		Auth       AuthSettings       `json:"auth"`
+		Encryption EncryptionSettings `json:"encryption"`
+	}
+	if err := json.Unmarshal([]byte(raw), &sections); err != nil {
+		return settings, true
+	}
+	if len(sections.Auth.problems()) > 0 || len(sections.Encryption.problems()) > 0 {
+		return settings, true
+	}
+	settings.Auth = sections.Auth
+	settings.Encryption = sections.Encryption
+	return settings, false
+}
+
+// lockedSettingsError returns why the settings are locked, or nil if they are usable
+func lockedSettingsError() error {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	if !settingsLocked {
+		return nil
+	}
+	return fmt.Errorf("%w: %v", ErrSettingsLocked, settingsErr)
+}
+
+// refreshSettings reloads the settings from SSUI and reports whether they changed since they were last loaded or saved.
+// An invalid settings document is reported through settingsError and the previous settings stay in use.
+func refreshSettings() bool {
+	raw := getOptionalStringSetting(settingsKey)
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	if currentSettings != nil && raw == loadedSettingsRaw {
+		return false
+	}
+	loaded, err := parseSettings(raw)
+	loadedSettingsRaw = raw
+	settingsErr = err
+	if err != nil && settingsSalvaged {
+		// There are no valid settings to keep, so salvage what the new document allows
+		salvaged, locked := salvageSettings(raw)
+		currentSettings, settingsLocked = &salvaged, locked
+		PluginLib.Log(fmt.Sprintf("Invalid %s setting, running on the default settings: %s", settingsKey, err.Error()), "Error")
+		return true
+	}
+	if err != nil {
+		PluginLib.Log(fmt.Sprintf("Ignoring invalid %s setting, keeping the previous settings: %s", settingsKey, err.Error()), "Error")
+		return false
+	}
+	currentSettings = &loaded
+	settingsSalvaged, settingsLocked = false, false
+	return true
+}
+
+// settingsError returns why the stored settings document was rejected, or "" if it is in use
+func settingsError() string {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	if settingsErr == nil {
+		return ""
+	}
+	return settingsErr.Error()
+}
+
+// parseSettings parses and validates a stored settings document. Without a document the
+// defaults are used, plus any of the older individual settings.
+func parseSettings(raw string) (Settings, error) {
+	settings := DefaultSettings()
+
+	if raw == "" {
+		settings.Encryption.Passphrase = getOptionalStringSetting("BackupEncryptionPassphrase")
+		settings.Encryption.KeyFile = getOptionalStringSetting("BackupEncryptionKeyFile")
+		settings.Webhooks = getWebhookTargetsSetting()
+		return settings, nil
+	}
+
+	if err := json.Unmarshal([]byte(raw), &settings); err != nil {
+		return Settings{}, fmt.Errorf("failed to parse %s setting: %w", settingsKey, err)
+	}
+	if err := settings.Validate(); err != nil {
+		return Settings{}, err
+	}
+	return settings, nil
+}
+
+// SaveSettings validates and persists new settings and hot-reloads the backup manager with them
+func SaveSettings(settings Settings) error {
+	if err := storeSettings(settings); err != nil {
+		return err
+	}
+	return ReloadBackupManagerFromConfig()
+}
+
+// storeSettings validates and persists new settings without reloading the backup manager
+func storeSettings(settings Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to encode settings: %w", err)
+	}
+
+	// Update the cache first so the config watcher doesn't take our own write for an external change
+	settingsMu.Lock()
+	previous, previousRaw, previousErr := currentSettings, loadedSettingsRaw, settingsErr
+	previousSalvaged, previousLocked := settingsSalvaged, settingsLocked
+	currentSettings = &settings
+	loadedSettingsRaw = string(data)
+	settingsErr = nil
+	settingsSalvaged, settingsLocked = false, false
+	settingsMu.Unlock()
+
+	if err := saveSettings(map[string]string{settingsKey: string(data)}); err != nil {
+		settingsMu.Lock()
+		if loadedSettingsRaw == string(data) {
+			currentSettings, loadedSettingsRaw, settingsErr = previous, previousRaw, previousErr
+			settingsSalvaged, settingsLocked = previousSalvaged, previousLocked
+		}
+		settingsMu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// UpdateSettings applies settings submitted through the API: redacted secrets are kept and
+// encryption can't be changed here since that requires re-encrypting the stored backups.
+func UpdateSettings(settings Settings) error {
+	current := GetSettings()
+	settings.unredact(current)
+
+	if settings.Encryption != current.Encryption {
+		return &SettingsValidationError{Problems: []string{"encryption settings can only be changed through /api/v1/backups/rotate-key"}}
+	}
+	return SaveSettings(settings)
+}
+
+// applyTo copies the user configurable values into a backup config
+func (s Settings) applyTo(cfg *BackupConfig) {
+	cfg.WaitTime = time.Duration(s.WaitTime)
+	cfg.StabilityWindow = time.Duration(s.StabilityWindow)
+	cfg.WatcherMode = s.Watcher.Mode
+	cfg.ScheduleInterval = time.Duration(s.Schedule.Interval)
+	cfg.ScheduleCron = s.Schedule.Cron
+	cfg.ScheduleOnlyWhenRunning = s.Schedule.OnlyWhenRunning
+	cfg.PollInterval = time.Duration(s.Watcher.PollInterval)
+	cfg.MaxBackups = s.Retention.MaxBackups
+	cfg.MaxAge = time.Duration(s.Retention.MaxAge)
+	cfg.ReplicationTargets = s.ReplicationTargets
+	cfg.Compression = s.Compression
+	cfg.QuotaMaxBytes = s.Quota.MaxBytes
+	cfg.QuotaMinFreeBytes = s.Quota.MinFreeBytes
+	cfg.QuotaPolicy = s.Quota.Policy
+	cfg.EncryptionPassphrase = s.Encryption.Passphrase
+	cfg.EncryptionKeyFile = s.Encryption.KeyFile
+	cfg.Webhooks = s.Webhooks
+}
+
+// isKnownEventType reports whether eventType is an event the manager publishes
+func isKnownEventType(eventType string) bool {
+	switch eventType {
+	case EventBackupDetected, EventCopyStarted, EventCopyFinished, EventCopyFailed, EventSnapshotCreated, EventQuotaExceeded,
+		EventRestoreStarted, EventRestoreProgress, EventRestoreFinished, EventRestoreFailed,
+		EventWatcherError, EventManagerReloaded:
+		return true
+	}
+	return false
+}
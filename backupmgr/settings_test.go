@@ -0,0 +1,152 @@
+package backupmgr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validToken returns an admin token that passes validation
+func validToken(name string) APIToken {
+	return APIToken{Name: name, Token: "0123456789abcdef0123", Scopes: []string{ScopeAdmin}}
+}
+
+func TestSettingsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(s *Settings)
+		// problem is a substring of the expected problem, empty if the settings are valid
+		problem string
+	}{
+		{"defaults", func(s *Settings) {}, ""},
+		{"wait time too short", func(s *Settings) { s.WaitTime = Duration(500 * time.Millisecond) }, "waitTime"},
+		{"unknown watcher mode", func(s *Settings) { s.Watcher.Mode = "inotify" }, "watcher.mode"},
+		{"interval and cron", func(s *Settings) {
+			s.Schedule.Interval = Duration(time.Hour)
+			s.Schedule.Cron = "0 * * * *"
+		}, "mutually exclusive"},
+		{"cron never matches", func(s *Settings) { s.Schedule.Cron = "0 0 31 4 *" }, "never matches"},
+		{"cron on leap day", func(s *Settings) { s.Schedule.Cron = "0 0 29 2 *" }, ""},
+		{"unknown quota policy", func(s *Settings) { s.Quota.Policy = "delete" }, "quota.policy"},
+		{"gzip compression", func(s *Settings) { s.Compression = CompressionGzip }, ""},
+		{"unknown compression", func(s *Settings) { s.Compression = "zstd" }, "compression"},
+		{"passphrase and key file", func(s *Settings) {
+			s.Encryption = EncryptionSettings{Passphrase: "secret", KeyFile: "/keys/backup.key"}
+		}, "mutually exclusive"},
+		{"webhook without scheme", func(s *Settings) { s.Webhooks = []WebhookTarget{{URL: "example.com/hook"}} }, "webhooks[0].url"},
+		{"short token", func(s *Settings) {
+			s.Auth.Tokens = []APIToken{{Name: "admin", Token: "short", Scopes: []string{ScopeAdmin}}}
+		}, "at least"},
+		{"duplicate token names", func(s *Settings) {
+			s.Auth.Tokens = []APIToken{validToken("admin"), validToken("admin")}
+		}, "unique"},
+		{"no admin token", func(s *Settings) {
+			token := validToken("reader")
+			token.Scopes = []string{ScopeRead}
+			s.Auth.Tokens = []APIToken{token}
+		}, "admin scope"},
+		{"invalid trusted proxy", func(s *Settings) { s.Auth.TrustedProxies = []string{"proxy.local"} }, "trustedProxies"},
+		{"trusted proxy range", func(s *Settings) { s.Auth.TrustedProxies = []string{"10.0.0.0/8", "::1"} }, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := DefaultSettings()
+			tt.modify(&settings)
+			err := settings.Validate()
+			if tt.problem == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want no error", err)
+				}
+				return
+			}
+			var validationErr *SettingsValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate() = %v, want a *SettingsValidationError", err)
+			}
+			if !strings.Contains(err.Error(), tt.problem) {
+				t.Errorf("Validate() = %v, want a problem mentioning %q", err, tt.problem)
+			}
+		})
+	}
+}
+
+func TestSettingsRedaction(t *testing.T) {
+	current := DefaultSettings()
+	current.Encryption.Passphrase = "correct horse"
+	current.Webhooks = []WebhookTarget{{URL: "https://example.com/hook", Secret: "hook secret"}}
+	current.Auth.Tokens = []APIToken{validToken("admin")}
+
+	redacted := current.Redacted()
+	if redacted.Encryption.Passphrase != redactedSecret || redacted.Webhooks[0].Secret != redactedSecret || redacted.Auth.Tokens[0].Token != redactedSecret {
+		t.Fatalf("Redacted() left secrets in place: %+v", redacted)
+	}
+	if current.Webhooks[0].Secret != "hook secret" || current.Auth.Tokens[0].Token != validToken("admin").Token {
+		t.Fatal("Redacted() modified the original settings")
+	}
+
+	tests := []struct {
+		name   string
+		modify func(s *Settings)
+		check  func(t *testing.T, s Settings)
+	}{
+		{"unchanged secrets are restored", func(s *Settings) {}, func(t *testing.T, s Settings) {
+			if s.Encryption.Passphrase != "correct horse" || s.Webhooks[0].Secret != "hook secret" || s.Auth.Tokens[0].Token != validToken("admin").Token {
+				t.Errorf("secrets were not restored: %+v", s)
+			}
+		}},
+		{"new secrets are kept", func(s *Settings) { s.Auth.Tokens[0].Token = "fedcba9876543210fedc" }, func(t *testing.T, s Settings) {
+			if s.Auth.Tokens[0].Token != "fedcba9876543210fedc" {
+				t.Errorf("token = %q, want the new token", s.Auth.Tokens[0].Token)
+			}
+		}},
+		{"secrets don't move to another webhook", func(s *Settings) { s.Webhooks[0].URL = "https://example.org/hook" }, func(t *testing.T, s Settings) {
+			if s.Webhooks[0].Secret != "" {
+				t.Errorf("secret = %q, want it dropped for a new URL", s.Webhooks[0].Secret)
+			}
+		}},
+		{"renamed tokens lose their secret", func(s *Settings) { s.Auth.Tokens[0].Name = "root" }, func(t *testing.T, s Settings) {
+			if s.Auth.Tokens[0].Token != "" {
+				t.Errorf("token = %q, want it dropped for a new name", s.Auth.Tokens[0].Token)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := current.Redacted()
+			tt.modify(&sent)
+			sent.unredact(current)
+			tt.check(t, sent)
+		})
+	}
+}
+
+func TestSalvageSettings(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		locked     bool
+		passphrase string
+		tokens     int
+	}{
+		{"unparseable document", `{"waitTime": `, true, "", 0},
+		{"invalid auth section", `{"auth": {"tokens": [{"name": "admin", "token": "short", "scopes": ["admin"]}]}}`, true, "", 0},
+		{"invalid encryption section", `{"encryption": {"passphrase": "a", "keyFile": "b"}}`, true, "", 0},
+		{"other section invalid", `{"waitTime": "1ms", "encryption": {"passphrase": "secret"}, "auth": {"tokens": [{"name": "admin", "token": "0123456789abcdef0123", "scopes": ["admin"]}]}}`, false, "secret", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, locked := salvageSettings(tt.raw)
+			if locked != tt.locked {
+				t.Fatalf("locked = %v, want %v", locked, tt.locked)
+			}
+			if settings.Encryption.Passphrase != tt.passphrase || len(settings.Auth.Tokens) != tt.tokens {
+				t.Errorf("salvaged passphrase %q and %d tokens, want %q and %d", settings.Encryption.Passphrase, len(settings.Auth.Tokens), tt.passphrase, tt.tokens)
+			}
+			if settings.WaitTime != DefaultSettings().WaitTime {
+				t.Errorf("waitTime = %v, want the default", time.Duration(settings.WaitTime))
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package backupmgr
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SteamServerUI/PluginLib"
+)
+
+// managerState describes what a BackupManager is currently doing
+type managerState string
+
+const (
+	stateInitializing      managerState = "initializing"
+	stateWaitingForSaveDir managerState = "waiting-for-save-dir"
+	stateWatching          managerState = "watching"
+	stateDegraded          managerState = "degraded"
+	stateStopped           managerState = "stopped"
+)
+
+// allowedTransitions lists the states each state may move to. Stopped is terminal:
+// a stopped manager is replaced by a new instance rather than restarted.
+var allowedTransitions = map[managerState][]managerState{
+	stateInitializing:      {stateWaitingForSaveDir, stateDegraded, stateStopped},
+	stateWaitingForSaveDir: {stateWatching, stateDegraded, stateStopped},
+	stateWatching:          {stateDegraded, stateStopped},
+	stateDegraded:          {stateWatching, stateStopped},
+	stateStopped:           {},
+}
+
+// ManagerStatus is a snapshot of a BackupManager's lifecycle state
+type ManagerStatus struct {
+	State        string     `json:"state"`
+	StateSince   time.Time  `json:"stateSince"`
+	LastError    string     `json:"lastError,omitempty"`
+	LastErrorAt  *time.Time `json:"lastErrorAt,omitempty"`
+	LastBackupAt *time.Time `json:"lastBackupAt,omitempty"`
+	// SettingsError explains why the stored settings were rejected, the previous ones stay in use
+	SettingsError string       `json:"settingsError,omitempty"`
+	Config        StatusConfig `json:"config"`
+}
+
+// StatusConfig is the active configuration as exposed over the API, without secrets
+type StatusConfig struct {
+	Identifier        string `json:"identifier"`
+	SaveName          string `json:"saveName"`
+	BackupDir         string `json:"backupDir"`
+	SafeBackupDir     string `json:"safeBackupDir"`
+	WaitTime          string `json:"waitTime"`
+	WatcherMode       string `json:"watcherMode"`
+	EncryptionEnabled bool   `json:"encryptionEnabled"`
+	Webhooks          int    `json:"webhooks"`
+}
+
+// setState moves the manager to a new state if the transition is allowed
+func (m *BackupManager) setState(state managerState) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	m.transitionLocked(state)
+}
+
+// transitionLocked performs a state transition; stateMu must be held
+func (m *BackupManager) transitionLocked(state managerState) {
+	if m.state == state {
+		return
+	}
+	for _, allowed := range allowedTransitions[m.state] {
+		if allowed == state {
+			m.state = state
+			m.stateSince = time.Now()
+			return
+		}
+	}
+	PluginLib.Log(fmt.Sprintf("%s Ignoring invalid state transition %s -> %s", m.config.Identifier, m.state, state), "Debug")
+}
+
+// getState returns the current manager state
+func (m *BackupManager) getState() managerState {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	return m.state
+}
+
+// recordError stores the error and marks the manager as degraded
+func (m *BackupManager) recordError(err error) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	m.lastError = err.Error()
+	m.lastErrorAt = time.Now()
+	m.transitionLocked(stateDegraded)
+}
+
+// recordBackup stores the time of a successful backup and clears a degraded state
+func (m *BackupManager) recordBackup() {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	m.lastBackupAt = time.Now()
+	if m.state == stateDegraded {
+		m.transitionLocked(stateWatching)
+	}
+}
+
+// Status returns a snapshot of the manager's lifecycle state
+func (m *BackupManager) Status() ManagerStatus {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+
+	status := ManagerStatus{
+		State:         string(m.state),
+		StateSince:    m.stateSince,
+		LastError:     m.lastError,
+		SettingsError: settingsError(),
+		Config: StatusConfig{
+			Identifier:        m.config.Identifier,
+			SaveName:          m.config.SaveName,
+			BackupDir:         m.config.BackupDir,
+			SafeBackupDir:     m.config.SafeBackupDir,
+			WaitTime:          m.config.WaitTime.String(),
+			WatcherMode:       m.config.WatcherMode,
+			EncryptionEnabled: m.config.encryptionEnabled(),
+			Webhooks:          len(m.config.Webhooks),
+		},
+	}
+	if !m.lastErrorAt.IsZero() {
+		lastErrorAt := m.lastErrorAt
+		status.LastErrorAt = &lastErrorAt
+	}
+	if !m.lastBackupAt.IsZero() {
+		lastBackupAt := m.lastBackupAt
+		status.LastBackupAt = &lastBackupAt
+	}
+	return status
+}
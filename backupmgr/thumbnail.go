@@ -0,0 +1,126 @@
+package backupmgr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/SteamServerUI/PluginLib"
+)
+
+// ErrNoThumbnail is returned for backups without a screenshot, which includes all legacy trios
+var ErrNoThumbnail = errors.New("backup has no thumbnail")
+
+// maxThumbnailSize is the largest image entry served as a thumbnail
+const maxThumbnailSize = 8 << 20
+
+// thumbnailCachePath returns where the thumbnail of a stored .save file is cached. The suffix keeps
+// the cache file out of isValidBackupFile so it is never taken for a backup. The thumbnail of an
+// encrypted backup is encrypted with the same key, so its cache keeps the encryption suffix.
+func thumbnailCachePath(backupFile string) string {
+	if isEncryptedFile(backupFile) {
+		return trimEncryptedSuffix(backupFile) + ".thumb" + encryptedSuffix
+	}
+	return backupFile + ".thumb"
+}
+
+// isImageEntry reports whether a zip entry looks like a screenshot
+func isImageEntry(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".png", ".jpg", ".jpeg":
+		return true
+	}
+	return false
+}
+
+// Thumbnail returns the screenshot stored in a .save backup. Thumbnails are cached next to the
+// backup so the archive is only opened once, encrypted like the backup itself.
+func (m *BackupManager) Thumbnail(id string) ([]byte, error) {
+	group, err := m.GetBackup(id)
+	if err != nil {
+		return nil, err
+	}
+	if !isSaveFile(group.BinFile) {
+		return nil, ErrNoThumbnail
+	}
+
+	cachePath := thumbnailCachePath(group.BinFile)
+	if info, err := os.Stat(cachePath); err == nil && !info.ModTime().Before(group.ModTime) {
+		if data, err := m.readThumbnailCache(cachePath); err == nil {
+			return data, nil
+		}
+	}
+
+	data, err := m.extractThumbnail(group.BinFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.writeThumbnailCache(cachePath, data); err != nil {
+		PluginLib.Log(fmt.Sprintf("%s Failed to cache thumbnail of backup %s: %s", m.config.Identifier, id, err.Error()), "Debug")
+	}
+	return data, nil
+}
+
+// readThumbnailCache reads a cached thumbnail, decrypting it if needed
+func (m *BackupManager) readThumbnailCache(cachePath string) ([]byte, error) {
+	r, err := m.openBackupReader(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, maxThumbnailSize))
+}
+
+// writeThumbnailCache caches a thumbnail, encrypting it if the cache path says so
+func (m *BackupManager) writeThumbnailCache(cachePath string, data []byte) error {
+	var secret []byte
+	if isEncryptedFile(cachePath) {
+		var err error
+		secret, err = m.config.encryptionSecret()
+		if err == nil && secret == nil {
+			err = errNoEncryptionKey
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	tmpPath := cachePath + ".tmp"
+	err := writeStream(tmpPath, bytes.NewReader(data), secret)
+	if err == nil {
+		err = os.Rename(tmpPath, cachePath)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+	}
+	return err
+}
+
+// extractThumbnail reads the first image entry of a .save archive
+func (m *BackupManager) extractThumbnail(backupFile string) ([]byte, error) {
+	r, closeZip, err := m.openBackupZip(backupFile)
+	if err != nil {
+		return nil, err
+	}
+	defer closeZip()
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !isImageEntry(f.Name) {
+			continue
+		}
+		if f.UncompressedSize64 > maxThumbnailSize {
+			return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrNoThumbnail, f.Name, maxThumbnailSize)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(io.LimitReader(rc, maxThumbnailSize))
+	}
+	return nil, ErrNoThumbnail
+}
@@ -3,6 +3,7 @@ package backupmgr
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,16 +13,56 @@ const (
 
 // BackupConfig holds configuration for backup operations
 type BackupConfig struct {
-	WorldName     string
-	BackupDir     string
-	SafeBackupDir string
-	WaitTime      time.Duration
-	Identifier    string
+	WorldName string
+	SaveName  string
+	// RunfileIdentifier is the SSUI runfile the paths were derived from, e.g. StationeersNewTerrain
+	RunfileIdentifier string
+	SaveDir           string // the live save folder holding the head save
+	BackupDir         string
+	SafeBackupDir     string
+	WaitTime          time.Duration
+	Identifier        string
+
+	// StabilityWindow requires a new autosave to stay unchanged this long before it is copied
+	StabilityWindow time.Duration
+
+	// Retention limits, zero disables a limit, see retention.go
+	MaxBackups int
+	MaxAge     time.Duration
+
+	// Storage limits for the backup set, see quota.go
+	QuotaMaxBytes     int64
+	QuotaMinFreeBytes int64
+	QuotaPolicy       string
+
+	// Additional directories every stored backup file is mirrored to, see replication.go
+	ReplicationTargets []string
+
+	// WatcherMode selects fsnotify, polling or automatic fallback, see watcher.go
+	WatcherMode  string
+	PollInterval time.Duration
+
+	// Scheduled snapshots of the live save, see scheduler.go
+	ScheduleInterval        time.Duration
+	ScheduleCron            string
+	ScheduleOnlyWhenRunning bool
+
+	// Compression of newly stored legacy trio files, see compression.go
+	Compression string
+
+	// Optional at-rest encryption of stored backups, see encryption.go
+	EncryptionPassphrase string
+	EncryptionKeyFile    string
+
+	// Outbound notifications, see webhooks.go
+	Webhooks []WebhookTarget
 }
 
 // BackupGroup represents a set of backup files
 type BackupGroup struct {
-	Index    int
+	Index int
+	// ID is the catalog key of the backup. Unlike Index it doesn't change as backups are pruned.
+	ID       string
 	BinFile  string
 	XMLFile  string
 	MetaFile string
@@ -32,8 +73,41 @@ type BackupGroup struct {
 type BackupManager struct {
 	config  BackupConfig
 	mu      sync.Mutex
-	watcher *fsWatcher
+	watcher backupWatcher
 	ctx     context.Context
 	cancel  context.CancelFunc
 	wg      sync.WaitGroup // Added for tracking goroutines
+
+	// lockHeld is set while this manager owns the SafeBackupDir lock, see lock.go. The lock is held
+	// from Start until Shutdown and borrowed by restores and deletes, lockRefs counts both.
+	lockHeld    atomic.Bool
+	lockMu      sync.Mutex
+	lockRefs    int
+	lockStarted bool          // Start holds a reference
+	lockStop    chan struct{} // closed to stop the heartbeat
+
+	// Files with a scheduled copy, used to de-duplicate bursts of watcher events
+	pendingMu sync.Mutex
+	pending   map[string]struct{}
+	// retired is set once the encryption key was rotated, nothing is stored until the manager is
+	// replaced. Guarded by mu.
+	retired bool
+	// abandon is closed when the shutdown deadline passed, pending copies are skipped then
+	abandon     chan struct{}
+	abandonOnce sync.Once
+
+	// setStats caches the stats of the stored backups for metric scrapes, see metrics.go
+	setStats atomic.Pointer[backupSetStats]
+
+	// Restore jobs waiting for the job worker, see jobs.go
+	jobQueue chan *trackedJob
+
+	// Lifecycle tracking, see state.go. Guarded by stateMu rather than mu so
+	// status reads never wait for a running copy or restore.
+	stateMu      sync.Mutex
+	state        managerState
+	stateSince   time.Time
+	lastError    string
+	lastErrorAt  time.Time
+	lastBackupAt time.Time
 }
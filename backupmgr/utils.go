@@ -31,6 +31,33 @@ func copyFile(src, dst string) error {
 	return destination.Sync()
 }
 
+// stabilityMaxChecks bounds how often waitForStableFile re-checks a file that keeps changing
+const stabilityMaxChecks = 10
+
+// waitForStableFile waits until the file's size and modification time stay unchanged for the given window.
+// It gives up after stabilityMaxChecks windows so a constantly changing file is still copied eventually.
+func waitForStableFile(path string, window time.Duration) {
+	if window <= 0 {
+		return
+	}
+
+	previous, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	for i := 0; i < stabilityMaxChecks; i++ {
+		time.Sleep(window)
+		current, err := os.Stat(path)
+		if err != nil {
+			return
+		}
+		if current.Size() == previous.Size() && current.ModTime().Equal(previous.ModTime()) {
+			return
+		}
+		previous = current
+	}
+}
+
 // parseBackupIndex extracts the backup index from a filename or assigns a synthetic index
 func parseBackupIndex(filename string, modTime time.Time, files []os.DirEntry) int {
 	// Try to extract index from old format (e.g., world(1).xml)
@@ -44,14 +71,14 @@ func parseBackupIndex(filename string, modTime time.Time, files []os.DirEntry) i
 	}
 
 	// For .save files, assign synthetic index based on mod time (newest eq highest)
-	if strings.HasSuffix(filename, ".save") {
+	if isSaveFile(filename) {
 		// Sort files by mod time to assign indexes
 		var sortedFiles []struct {
 			name    string
 			modTime time.Time
 		}
 		for _, file := range files {
-			if !strings.HasSuffix(file.Name(), ".save") {
+			if !isSaveFile(file.Name()) {
 				continue
 			}
 			info, err := file.Info()
@@ -4,21 +4,61 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/SteamServerUI/PluginLib"
 	"github.com/fsnotify/fsnotify"
 )
 
+// Watcher modes selectable through the plugin settings
+const (
+	WatcherModeAuto     = "auto"     // fsnotify, falling back to polling if it can't be set up
+	WatcherModeFsnotify = "fsnotify" // fsnotify only
+	WatcherModePoll     = "poll"     // always poll
+)
+
+// backupWatcher delivers file system events for the autosave directory
+type backupWatcher interface {
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+	close()
+}
+
+// newBackupWatcher creates the watcher selected by the config
+func newBackupWatcher(cfg BackupConfig, identifier string) (backupWatcher, error) {
+	switch cfg.WatcherMode {
+	case WatcherModePoll:
+		PluginLib.Log(fmt.Sprintf("%s Using polling watcher every %s for %s", identifier, cfg.PollInterval, cfg.BackupDir), "Info")
+		return newPollWatcher(cfg.BackupDir, cfg.PollInterval)
+	case WatcherModeFsnotify:
+		return newFsWatcher(cfg.BackupDir, identifier, false)
+	default:
+		// Any directory that can't be watched means missed autosaves, polling covers all of them
+		w, err := newFsWatcher(cfg.BackupDir, identifier, true)
+		if err == nil {
+			return w, nil
+		}
+		PluginLib.Log(fmt.Sprintf("%s fsnotify setup failed, falling back to polling: %s", identifier, err.Error()), "Error")
+		return newPollWatcher(cfg.BackupDir, cfg.PollInterval)
+	}
+}
+
 // fsWatcher wraps fsnotify.Watcher with additional safety
 type fsWatcher struct {
-	watcher *fsnotify.Watcher
-	events  chan fsnotify.Event
-	errors  chan error
-	done    chan struct{}
+	watcher    *fsnotify.Watcher
+	events     chan fsnotify.Event
+	errors     chan error
+	done       chan struct{}
+	identifier string
+	dirs       map[string]struct{} // watched directories, only touched by forwardEvents after construction
+	root       string
+	// strict fails on any directory that can't be watched instead of only on the root
+	strict bool
 }
 
-// newFsWatcher creates a new file system watcher
-func newFsWatcher(path string, identifier string) (*fsWatcher, error) {
+// newFsWatcher creates a new file system watcher. With strict set it fails if any directory
+// below path can't be watched, otherwise only if path itself can't be.
+func newFsWatcher(path string, identifier string, strict bool) (*fsWatcher, error) {
 	// Normalize path
 	normalizedPath := filepath.Clean(path)
 	PluginLib.Log(fmt.Sprintf("%s Creating watcher for path: %s", identifier, normalizedPath), "Debug")
@@ -29,34 +69,99 @@ func newFsWatcher(path string, identifier string) (*fsWatcher, error) {
 	}
 	PluginLib.Log(fmt.Sprintf("%s Watcher created successfully", identifier), "Debug")
 
+	w := &fsWatcher{
+		watcher:    watcher,
+		events:     make(chan fsnotify.Event),
+		errors:     make(chan error),
+		done:       make(chan struct{}),
+		identifier: identifier,
+		dirs:       make(map[string]struct{}),
+		root:       normalizedPath,
+		strict:     strict,
+	}
+
 	// Watch the root save path and all subdirectories
-	err = filepath.WalkDir(normalizedPath, func(subPath string, d os.DirEntry, err error) error {
+	if err := w.addTree(normalizedPath); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("%s failed to add subdirectories to watcher: %w", identifier, err)
+	}
+
+	go w.forwardEvents()
+	return w, nil
+}
+
+// Events returns the channel of forwarded fsnotify events
+func (w *fsWatcher) Events() <-chan fsnotify.Event {
+	return w.events
+}
+
+// Errors returns the channel of forwarded fsnotify errors
+func (w *fsWatcher) Errors() <-chan error {
+	return w.errors
+}
+
+// addTree adds a directory and all its subdirectories to the watcher. Failing to watch the watcher's
+// root, or any directory in strict mode, is returned, other failures are only logged.
+func (w *fsWatcher) addTree(root string) error {
+	return filepath.WalkDir(root, func(subPath string, d os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 		if d.IsDir() {
-			if err := watcher.Add(subPath); err != nil {
-				PluginLib.Log(fmt.Sprintf("%s Failed to add subdir %s to watcher: %s", identifier, subPath, err.Error()), "Error")
+			if err := w.watcher.Add(subPath); err != nil {
+				if w.strict || subPath == w.root {
+					return fmt.Errorf("failed to watch %s: %w", subPath, err)
+				}
+				PluginLib.Log(fmt.Sprintf("%s Failed to add subdir %s to watcher: %s", w.identifier, subPath, err.Error()), "Error")
 			} else {
-				PluginLib.Log(fmt.Sprintf("%s Added subdir %s to watcher", identifier, subPath), "Debug")
+				w.dirs[subPath] = struct{}{}
+				PluginLib.Log(fmt.Sprintf("%s Added subdir %s to watcher", w.identifier, subPath), "Debug")
 			}
 		}
 		return nil
 	})
-	if err != nil {
-		watcher.Close()
-		return nil, fmt.Errorf("%s failed to add subdirectories to watcher: %w", identifier, err)
-	}
+}
 
-	w := &fsWatcher{
-		watcher: watcher,
-		events:  make(chan fsnotify.Event),
-		errors:  make(chan error),
-		done:    make(chan struct{}),
+// removeTree drops the watches for a directory and everything below it
+func (w *fsWatcher) removeTree(root string) {
+	prefix := root + string(os.PathSeparator)
+	for dir := range w.dirs {
+		if dir != root && !strings.HasPrefix(dir, prefix) {
+			continue
+		}
+		// Removed directories are usually dropped by fsnotify already, so errors are expected here
+		_ = w.watcher.Remove(dir)
+		delete(w.dirs, dir)
+		PluginLib.Log(fmt.Sprintf("%s Removed subdir %s from watcher", w.identifier, dir), "Debug")
 	}
+}
 
-	go w.forwardEvents()
-	return w, nil
+// trackDirectories keeps the watch list in sync with directories created, renamed or removed below the root.
+// For a new directory it returns synthetic Create events for files written before its watch was added.
+func (w *fsWatcher) trackDirectories(event fsnotify.Event) []fsnotify.Event {
+	path := filepath.Clean(event.Name)
+	switch {
+	case event.Op&fsnotify.Create == fsnotify.Create:
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if err := w.addTree(path); err != nil {
+			PluginLib.Log(fmt.Sprintf("%s Failed to watch new directory %s: %s", w.identifier, path, err.Error()), "Error")
+			return nil
+		}
+		var missed []fsnotify.Event
+		filepath.WalkDir(path, func(subPath string, d os.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				missed = append(missed, fsnotify.Event{Name: subPath, Op: fsnotify.Create})
+			}
+			return nil
+		})
+		return missed
+	case event.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		w.removeTree(path)
+	}
+	return nil
 }
 
 // forwardEvents forwards events and errors from the underlying watcher
@@ -69,11 +174,13 @@ func (w *fsWatcher) forwardEvents() {
 				close(w.events)
 				return
 			}
-			select {
-			case w.events <- event:
-				// Successfully sent event
-			case <-w.done:
-				return
+			for _, e := range append([]fsnotify.Event{event}, w.trackDirectories(event)...) {
+				select {
+				case w.events <- e:
+					// Successfully sent event
+				case <-w.done:
+					return
+				}
 			}
 		case err, ok := <-w.watcher.Errors:
 			if !ok {
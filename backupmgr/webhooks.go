@@ -0,0 +1,291 @@
+package backupmgr
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SteamServerUI/PluginLib"
+)
+
+// Webhook payload formats
+const (
+	WebhookFormatJSON    = "json"
+	WebhookFormatDiscord = "discord"
+)
+
+const (
+	webhookMaxAttempts    = 5
+	webhookInitialBackoff = 2 * time.Second
+	webhookTimeout        = 10 * time.Second
+	webhookSignatureHdr   = "X-SBM-Signature"
+	webhookEventHdr       = "X-SBM-Event"
+)
+
+// EventWebhookTest is only sent by the test-delivery endpoint
+const EventWebhookTest = "webhook.test"
+
+// webhookEvents are the event types forwarded to webhooks unless a target lists its own
+var webhookEvents = []string{EventCopyFinished, EventCopyFailed, EventRestoreFinished, EventRestoreFailed, EventWatcherError}
+
+// WebhookTarget is a single outbound webhook configured through the BackupWebhooks plugin setting
+type WebhookTarget struct {
+	URL    string   `json:"url"`
+	Format string   `json:"format,omitempty"` // "json" (default) or "discord"
+	Secret string   `json:"secret,omitempty"` // optional HMAC-SHA256 signing secret
+	Events []string `json:"events,omitempty"` // optional event filter, defaults to webhookEvents
+}
+
+// wants reports whether the target should receive the given event type
+func (t WebhookTarget) wants(eventType string) bool {
+	if eventType == EventWebhookTest {
+		return true
+	}
+	filter := t.Events
+	if len(filter) == 0 {
+		filter = webhookEvents
+	}
+	for _, e := range filter {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryResult reports the outcome of a single delivery
+type WebhookDeliveryResult struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// webhookNotifier forwards events from the broker to the configured webhook targets
+type webhookNotifier struct {
+	mu       sync.Mutex
+	targets  []WebhookTarget
+	client   *http.Client
+	once     sync.Once
+	draining bool // set by drain, no new deliveries are started afterwards
+
+	// Deliveries run on ctx so drain can abandon them, wg tracks the ones in flight
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+var webhooks = newWebhookNotifier()
+
+// newWebhookNotifier creates a notifier without targets
+func newWebhookNotifier() *webhookNotifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &webhookNotifier{client: &http.Client{Timeout: webhookTimeout}, ctx: ctx, cancel: cancel}
+}
+
+// setTargets replaces the configured targets and starts the notifier on first use
+func (n *webhookNotifier) setTargets(targets []WebhookTarget) {
+	n.mu.Lock()
+	n.targets = targets
+	n.mu.Unlock()
+
+	n.once.Do(func() {
+		go n.run(events.subscribe())
+	})
+}
+
+// currentTargets returns a snapshot of the configured targets
+func (n *webhookNotifier) currentTargets() []WebhookTarget {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]WebhookTarget(nil), n.targets...)
+}
+
+// run delivers events until the subscription is closed
+func (n *webhookNotifier) run(sub chan Event) {
+	for event := range sub {
+		for _, target := range n.currentTargets() {
+			if target.wants(event.Type) {
+				n.startDelivery(target, event)
+			}
+		}
+	}
+}
+
+// startDelivery delivers an event in the background unless the notifier is draining
+func (n *webhookNotifier) startDelivery(target WebhookTarget, event Event) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.draining {
+		return
+	}
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.deliverWithRetry(target, event)
+	}()
+}
+
+// deliverWithRetry delivers an event, retrying with exponential backoff on failure
+func (n *webhookNotifier) deliverWithRetry(target WebhookTarget, event Event) {
+	backoff := webhookInitialBackoff
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		result := n.deliver(n.ctx, target, event)
+		if result.Error == "" {
+			return
+		}
+		if attempt == webhookMaxAttempts || n.ctx.Err() != nil {
+			PluginLib.Log(fmt.Sprintf("Webhook delivery of %s to %s failed after %d attempts: %s", event.Type, target.URL, attempt, result.Error), "Error")
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-n.ctx.Done():
+		}
+		backoff *= 2
+	}
+}
+
+// drain stops accepting new deliveries and waits up to timeout for the ones in flight, including
+// their retries. Deliveries still running after that are abandoned. It reports whether all finished.
+func (n *webhookNotifier) drain(timeout time.Duration) bool {
+	n.mu.Lock()
+	n.draining = true
+	n.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+	}
+	n.cancel()
+	<-done
+	return false
+}
+
+// deliver performs a single delivery attempt
+func (n *webhookNotifier) deliver(ctx context.Context, target WebhookTarget, event Event) WebhookDeliveryResult {
+	result := WebhookDeliveryResult{URL: target.URL}
+
+	body, err := webhookPayload(target.Format, event)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHdr, event.Type)
+	if target.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(target.Secret))
+		mac.Write(body)
+		req.Header.Set(webhookSignatureHdr, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Error = fmt.Sprintf("unexpected status %s", resp.Status)
+	}
+	return result
+}
+
+// SendTestWebhook sends a single test delivery to every configured target and reports the results
+func SendTestWebhook(ctx context.Context) []WebhookDeliveryResult {
+	event := Event{
+		Type:    EventWebhookTest,
+		Message: "Test notification from the Stationeers Backup Manager",
+		Time:    time.Now(),
+	}
+
+	results := []WebhookDeliveryResult{}
+	for _, target := range webhooks.currentTargets() {
+		results = append(results, webhooks.deliver(ctx, target, event))
+	}
+	return results
+}
+
+// webhookPayload renders an event in the requested format
+func webhookPayload(format string, event Event) ([]byte, error) {
+	switch format {
+	case "", WebhookFormatJSON:
+		return json.Marshal(event)
+	case WebhookFormatDiscord:
+		return json.Marshal(discordPayload(event))
+	default:
+		return nil, fmt.Errorf("unknown webhook format %q", format)
+	}
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description"`
+	Color       int                 `json:"color"`
+	Timestamp   string              `json:"timestamp"`
+	Fields      []discordEmbedField `json:"fields,omitempty"`
+}
+
+type discordWebhook struct {
+	Username string         `json:"username"`
+	Embeds   []discordEmbed `json:"embeds"`
+}
+
+// discordPayload renders an event as a Discord-compatible embed
+func discordPayload(event Event) discordWebhook {
+	color := 0x2ecc71 // green
+	switch event.Type {
+	case EventCopyFailed, EventRestoreFailed, EventWatcherError:
+		color = 0xe74c3c // red
+	case EventWebhookTest:
+		color = 0x3498db // blue
+	}
+
+	embed := discordEmbed{
+		Title:       event.Type,
+		Description: event.Message,
+		Color:       color,
+		Timestamp:   event.Time.Format(time.RFC3339),
+	}
+	keys := make([]string, 0, len(event.Data))
+	for key := range event.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: key, Value: fmt.Sprint(event.Data[key]), Inline: true})
+	}
+	if event.Identifier != "" {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "manager", Value: event.Identifier, Inline: true})
+	}
+
+	return discordWebhook{Username: "Stationeers Backup Manager", Embeds: []discordEmbed{embed}}
+}
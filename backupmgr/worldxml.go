@@ -0,0 +1,336 @@
+package backupmgr
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+/*
+Stationeers stores a world as world.xml (the full world state, often hundreds of megabytes) and
+world_meta.xml (a handful of fields shown in the load menu). world.xml has the shape
+
+	<WorldData>
+	  <GameVersion>0.2.5000</GameVersion>          top-level setting
+	  <WorldSettings><Difficulty>...</Difficulty>  nested settings
+	  <Things>                                     collection
+	    <ThingSaveData xsi:type="StructureSaveData"><PrefabName>StructureWallSmall</PrefabName>...</ThingSaveData>
+	  </Things>
+	  <Atmospheres><AtmosphereSaveData>...</AtmosphereSaveData></Atmospheres>
+	  <Rooms><Room>...</Room></Rooms>
+	</WorldData>
+
+The helpers here parse it as a token stream so memory use does not grow with the world size.
+*/
+
+const (
+	// maxSettingsPerSection caps the leaf values collected below a single top-level element
+	maxSettingsPerSection = 200
+	// maxMetaFields caps the number of fields read from world_meta.xml
+	maxMetaFields = 1000
+)
+
+// WorldSummary is a structural summary of a world.xml file
+type WorldSummary struct {
+	Root string `json:"root"`
+	// Settings holds top-level leaf values by path, e.g. "GameVersion" or "WorldSettings/Difficulty"
+	Settings map[string]string `json:"settings"`
+	// Counts holds the number of items in each collection by type, e.g. "Things/StructureSaveData"
+	Counts map[string]int `json:"counts"`
+	// Prefabs holds the number of collection items by their PrefabName, e.g. "StructureWallSmall"
+	Prefabs map[string]int `json:"prefabs"`
+	// Elements is the total number of XML elements in the file
+	Elements int64 `json:"elements"`
+}
+
+// itemType returns the type of a collection item: its xsi:type if set, otherwise its element name
+func itemType(start xml.StartElement) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "type" && (attr.Name.Space == "xsi" || strings.HasSuffix(attr.Name.Space, "XMLSchema-instance")) {
+			// Drop a namespace prefix like "d2p1:StructureSaveData"
+			if i := strings.LastIndex(attr.Value, ":"); i >= 0 {
+				return attr.Value[i+1:]
+			}
+			return attr.Value
+		}
+	}
+	return start.Name.Local
+}
+
+// summarizeWorldXML streams a world.xml file and summarizes its structure.
+// Direct children of the root whose children repeat or have elements of their own are collections
+// and their items are counted by type; the leaf values of all other top-level elements are settings.
+func summarizeWorldXML(r io.Reader) (WorldSummary, error) {
+	summary := WorldSummary{Settings: make(map[string]string), Counts: make(map[string]int), Prefabs: make(map[string]int)}
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+
+	// section is the open direct child of the root
+	type section struct {
+		name       string
+		childNames map[string]bool
+		collection bool
+		counts     map[string]int
+		settings   map[string]string
+	}
+	var (
+		stack   []string // names of the open elements
+		current *section
+		text    strings.Builder
+		isLeaf  bool
+	)
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return summary, fmt.Errorf("failed to parse world.xml: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			summary.Elements++
+			switch len(stack) + 1 {
+			case 1:
+				summary.Root = t.Name.Local
+			case 2:
+				current = &section{
+					name:       t.Name.Local,
+					childNames: make(map[string]bool),
+					counts:     make(map[string]int),
+					settings:   make(map[string]string),
+				}
+			case 3:
+				if current.childNames[t.Name.Local] {
+					current.collection = true
+				}
+				current.childNames[t.Name.Local] = true
+				current.counts[itemType(t)]++
+			}
+			stack = append(stack, t.Name.Local)
+			text.Reset()
+			isLeaf = true
+
+		case xml.CharData:
+			if isLeaf && text.Len() < 1024 {
+				text.Write(t)
+			}
+
+		case xml.EndElement:
+			depth := len(stack)
+			if isLeaf && (depth == 2 || depth == 3) {
+				key := strings.Join(stack[1:], "/")
+				value := strings.TrimSpace(text.String())
+				if depth == 2 {
+					// Empty top-level elements are usually emptied collections like <Rooms />
+					if value != "" {
+						summary.Settings[key] = value
+					}
+				} else if !current.collection && len(current.settings) < maxSettingsPerSection {
+					current.settings[key] = value
+				}
+			}
+			if depth == 4 && isLeaf && stack[3] == "PrefabName" {
+				if prefab := strings.TrimSpace(text.String()); prefab != "" {
+					summary.Prefabs[prefab]++
+				}
+			}
+			if depth == 3 && !isLeaf {
+				// Children with their own elements are items, even if there is only one of them
+				current.collection = true
+			}
+			if depth == 2 {
+				if current.collection {
+					for typ, count := range current.counts {
+						summary.Counts[current.name+"/"+typ] += count
+					}
+				} else {
+					for key, value := range current.settings {
+						summary.Settings[key] = value
+					}
+				}
+				current = nil
+			}
+			stack = stack[:len(stack)-1]
+			isLeaf = false
+		}
+	}
+	return summary, nil
+}
+
+// readXMLFields reads all leaf values of a small XML document such as world_meta.xml by path.
+// Repeated elements get an index suffix, e.g. "Mods/Mod[2]".
+func readXMLFields(r io.Reader) (map[string]string, error) {
+	fields := make(map[string]string)
+	seen := make(map[string]int)
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+
+	var stack []string
+	var text strings.Builder
+	isLeaf := false
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return fields, nil
+		}
+		if err != nil {
+			return fields, fmt.Errorf("failed to parse XML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := path.Join(append(stack, t.Name.Local)...)
+			seen[name]++
+			if n := seen[name]; n > 1 {
+				name = fmt.Sprintf("%s[%d]", name, n)
+			}
+			stack = append(stack, path.Base(name))
+			text.Reset()
+			isLeaf = true
+		case xml.CharData:
+			if isLeaf && text.Len() < 4096 {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if isLeaf && len(fields) < maxMetaFields {
+				fields[path.Join(stack...)] = strings.TrimSpace(text.String())
+			}
+			stack = stack[:len(stack)-1]
+			isLeaf = false
+		}
+	}
+}
+
+// BackupEntry is a file inside a backup: a zip entry of a .save or one file of a legacy trio
+type BackupEntry struct {
+	Name           string `json:"name"`
+	Size           int64  `json:"size"`
+	CompressedSize int64  `json:"compressedSize,omitempty"`
+}
+
+// openedBackup is a backup opened for reading several of its files. The archive of a .save backup,
+// and the decryption of an encrypted one, is set up once and shared by every read.
+type openedBackup struct {
+	m     *BackupManager
+	group BackupGroup
+	zip   *zip.Reader // nil for legacy trios
+	close func() error
+}
+
+// openBackup opens a backup for reading its files, the caller must close it
+func (m *BackupManager) openBackup(group BackupGroup) (*openedBackup, error) {
+	backup := &openedBackup{m: m, group: group, close: func() error { return nil }}
+	if isSaveFile(group.BinFile) {
+		r, closeZip, err := m.openBackupZip(group.BinFile)
+		if err != nil {
+			return nil, err
+		}
+		backup.zip, backup.close = r, closeZip
+	}
+	return backup, nil
+}
+
+func (b *openedBackup) Close() error {
+	return b.close()
+}
+
+// entries lists the files inside the backup
+func (b *openedBackup) entries() ([]BackupEntry, error) {
+	if b.zip != nil {
+		entries := make([]BackupEntry, 0, len(b.zip.File))
+		for _, f := range b.zip.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			entries = append(entries, BackupEntry{Name: f.Name, Size: int64(f.UncompressedSize64), CompressedSize: int64(f.CompressedSize64)})
+		}
+		return entries, nil
+	}
+
+	var entries []BackupEntry
+	for _, file := range []struct{ name, path string }{
+		{"world.bin", b.group.BinFile}, {"world.xml", b.group.XMLFile}, {"world_meta.xml", b.group.MetaFile},
+	} {
+		info, err := os.Stat(file.path)
+		if err != nil {
+			return nil, err
+		}
+		// Encrypted sizes include the small encryption header and tag, close enough for comparisons
+		entries = append(entries, BackupEntry{Name: file.name, Size: info.Size()})
+	}
+	return entries, nil
+}
+
+// openWorldFile opens world.xml or world_meta.xml of the backup, from the .save zip or the legacy trio
+func (b *openedBackup) openWorldFile(name string) (io.ReadCloser, error) {
+	if b.zip != nil {
+		for _, f := range b.zip.File {
+			if path.Base(f.Name) == name {
+				return f.Open()
+			}
+		}
+		return nil, fmt.Errorf("%s not found in %s", name, path.Base(b.group.BinFile))
+	}
+
+	file := b.group.XMLFile
+	if name == "world_meta.xml" {
+		file = b.group.MetaFile
+	}
+	return b.m.openBackupReader(file)
+}
+
+// worldMeta reads the fields of the backup's world_meta.xml
+func (b *openedBackup) worldMeta() (map[string]string, error) {
+	r, err := b.openWorldFile("world_meta.xml")
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return readXMLFields(r)
+}
+
+// summarizeWorld streams the backup's world.xml into a structural summary
+func (b *openedBackup) summarizeWorld() (WorldSummary, error) {
+	r, err := b.openWorldFile("world.xml")
+	if err != nil {
+		return WorldSummary{}, err
+	}
+	defer r.Close()
+	return summarizeWorldXML(r)
+}
+
+// openWorldFile opens world.xml or world_meta.xml of a backup for a single read
+func (m *BackupManager) openWorldFile(group BackupGroup, name string) (io.ReadCloser, error) {
+	backup, err := m.openBackup(group)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := backup.openWorldFile(name)
+	if err != nil {
+		backup.Close()
+		return nil, err
+	}
+	return &multiCloser{ReadCloser: rc, closeAlso: backup.Close}, nil
+}
+
+// multiCloser closes an additional resource after the wrapped reader
+type multiCloser struct {
+	io.ReadCloser
+	closeAlso func() error
+}
+
+func (c *multiCloser) Close() error {
+	err := c.ReadCloser.Close()
+	if closeErr := c.closeAlso(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/SteamServerUI/PluginLib"
 	"github.com/SteamServerUI/StationeersBackupManager/api"
@@ -15,6 +21,9 @@ import (
 //go:embed assets/*
 var assets embed.FS
 
+// shutdownTimeout is how long pending copies and restores get to finish once SSUI stops the plugin
+const shutdownTimeout = 30 * time.Second
+
 var (
 	settingsResponse PluginLib.SettingsResponse
 	wg               sync.WaitGroup
@@ -27,13 +36,31 @@ func main() {
 
 	PluginLib.InitConfig(global.PluginName, global.DefaultLogLevel)
 
-	if err := backupmgr.ReloadBackupManagerFromConfig(); err != nil {
+	if err := backupmgr.ReloadBackupManagerFromConfig(); errors.Is(err, backupmgr.ErrSettingsLocked) {
+		// Keep running so the fixed settings are picked up by the config watcher
+		PluginLib.Log("Backup manager not started: "+err.Error(), "Error")
+	} else if err != nil {
 		PluginLib.Log("Failed to reload backup manager: " + err.Error())
 		return
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	watcherDone := backupmgr.WatchConfigChanges(ctx)
+
 	ExposeAPI(&wg)
-	wg.Wait()
+	<-ctx.Done()
+	stop() // a second signal kills the process right away
+
+	PluginLib.Log("Received stop signal, shutting down backup manager...", "Info")
+	// A reload started before the signal must not swap in a new manager after the shutdown
+	<-watcherDone
+	if err := backupmgr.ShutdownGlobalBackupManager(shutdownTimeout); err != nil {
+		PluginLib.Log("Backup manager did not shut down cleanly: "+err.Error(), "Error")
+		os.Exit(1)
+	}
+	PluginLib.Log("Backup manager stopped, exiting", "Info")
 }
 
 func GetGameserverRunningStatus() {
@@ -104,11 +131,9 @@ func ExposeAPI(wg *sync.WaitGroup) {
 	PluginLib.RegisterRoute("/", api.HandleBackupManagerIndex)
 	PluginLib.RegisterRoute("/js/backups.js", api.HandleBackupsJS)
 
-	PluginLib.RegisterRoute("/api/v1/backups", backupHandler.ListBackupsHandler)
-	PluginLib.RegisterRoute("/api/v1/backups/restore", backupHandler.RestoreBackupHandler)
+	backupHandler.Register(PluginLib.RegisterRoute)
 	PluginLib.ExposeAPI(wg)
 	PluginLib.RegisterPluginAPI()
-	wg.Add(1)
 }
 
 func getRfIdentifierFromSSUIRunfile() (string, error) {